@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type settings struct {
+	latency       time.Duration
+	jitter        time.Duration
+	errorRate     float64
+	malformedRate float64
+	partialRate   float64
+	truncateRate  float64
+}
+
+type request struct {
+	Action    string `json:"action"`
+	RequestId string `json:"request_id"`
+	Model     string `json:"model"`
+	Prompt    string `json:"prompt"`
+	System    string `json:"system"`
+}
+
+type frame struct {
+	RequestId string `json:"request_id,omitempty"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Status    string `json:"status,omitempty"`
+}
+
+func main() {
+	addr := flag.String("addr", ":8081", "listen address")
+	scriptPath := flag.String("script", os.Getenv("FAKELLM_SCRIPT"), "JSON file of {match, response} rules checked before the built-in rules")
+	config := settings{}
+	flag.DurationVar(&config.latency, "latency", 0, "base delay before answering")
+	flag.DurationVar(&config.jitter, "jitter", 0, "random extra delay added to latency")
+	flag.Float64Var(&config.errorRate, "error-rate", 0, "probability of answering with an error frame")
+	flag.Float64Var(&config.malformedRate, "malformed-rate", 0, "probability of answering with malformed JSON")
+	flag.Float64Var(&config.partialRate, "partial-rate", 0, "probability of sending status frames before the result")
+	flag.Float64Var(&config.truncateRate, "truncate-rate", 0, "probability of cutting the result off midway")
+	flag.Parse()
+
+	script, err := loadScript(*scriptPath)
+	if err != nil {
+		fmt.Println("Error loading script:", err)
+		os.Exit(1)
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Println("Error upgrading connection", err)
+			return
+		}
+		defer conn.Close()
+
+		serve(conn, script, config)
+	})
+
+	fmt.Println("Starting fake llm gateway on", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func serve(conn *websocket.Conn, script []rule, config settings) {
+	var writeMu sync.Mutex
+	write := func(f frame) {
+		data, _ := json.Marshal(f)
+
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			log.Println("Error writing frame", err)
+		}
+	}
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		req := request{}
+		if err := json.Unmarshal(message, &req); err != nil || req.Action != "runModel" {
+			write(frame{Error: "invalid request"})
+			continue
+		}
+
+		go func() {
+			delay := config.latency
+			if config.jitter > 0 {
+				delay += time.Duration(rand.Int63n(int64(config.jitter)))
+			}
+			time.Sleep(delay)
+
+			if rand.Float64() < config.partialRate {
+				write(frame{RequestId: req.RequestId, Status: "running"})
+			}
+
+			if rand.Float64() < config.errorRate {
+				write(frame{RequestId: req.RequestId, Error: "injected failure"})
+				return
+			}
+
+			result := reply(script, req.System, req.Prompt)
+			if rand.Float64() < config.malformedRate {
+				result = "Sure! Here you go: {\"oops\": [1, 2,, 'three'"
+			} else if rand.Float64() < config.truncateRate && len(result) > 1 {
+				result = result[:len(result)/2]
+			}
+
+			write(frame{RequestId: req.RequestId, Result: result})
+		}()
+	}
+}
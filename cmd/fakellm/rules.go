@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+)
+
+type rule struct {
+	Match    string `json:"match"`
+	Response string `json:"response"`
+}
+
+func loadScript(path string) ([]rule, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []rule{}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func mustJson(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(data)
+}
+
+var builtinRules = []struct {
+	match   string
+	respond func(prompt string) string
+}{
+	{"'intro'", func(string) string {
+		return mustJson(map[string]string{"name": "Board Game Night Crew", "intro": "Welcome, everyone! You all love board games and a good hike, so why not start with a game night?"})
+	}},
+	{"'conversation_starters'", func(string) string {
+		return mustJson(map[string]any{
+			"reason":               "You both light up talking about board games and late-night coding sessions, so you'd have plenty to talk about.",
+			"complementary_traits": []string{"a planner paired with someone spontaneous"},
+			"conversation_starters": []string{
+				"What's the last board game that surprised you?",
+				"Which trail would you take a newcomer on?",
+				"What side project are you most excited about right now?",
+			},
+		})
+	}},
+	{"'best_match'", bestMatch},
+	{"'matches'", candidateMatches},
+	{"'explanation'", func(string) string {
+		return mustJson(map[string]string{"explanation": "You both light up talking about board games and late-night coding sessions, so you'd have plenty to talk about."})
+	}},
+	{"'interests'", func(string) string {
+		return mustJson(map[string]any{"interests": []map[string]any{
+			{"interest": "board games", "level": 0.9, "emoji": "🎲"},
+			{"interest": "hiking", "level": 0.7, "emoji": "🥾"},
+			{"interest": "programming", "level": 0.8, "emoji": "💻"},
+		}})
+	}},
+	{"'openness'", func(string) string {
+		return "Analysis: the user seems curious and organized.\n```json\n" + mustJson(map[string]float64{
+			"openness": 4, "conscientiousness": 3.5, "extroversion": 2.5, "agreeableness": 4, "neuroticism": 2,
+		}) + "\n```"
+	}},
+	{"'active_listening'", func(string) string {
+		return mustJson(map[string]float64{
+			"active_listening": 0.7, "teamwork": 0.6, "responsibility": 0.8, "dependability": 0.8, "leadership": 0.5,
+			"motivation": 0.7, "flexibility": 0.6, "patience": 0.6, "empathy": 0.7,
+		})
+	}},
+	{"'skills'", func(string) string {
+		return mustJson(map[string]any{"skills": []map[string]any{
+			{"skill": "go programming", "level": 0.8},
+			{"skill": "cooking", "level": 0.5},
+		}})
+	}},
+	{"'goals'", func(string) string {
+		return mustJson(map[string]any{"goals": []map[string]any{
+			{"goal": "ship a side project", "importance": 0.8},
+			{"goal": "run a half marathon", "importance": 0.6},
+		}})
+	}},
+	{"'core_values'", func(string) string {
+		return mustJson(map[string]any{"analysis": "values honesty {and} growth", "core_values": []map[string]any{
+			{"value": "honesty", "importance": 0.9},
+			{"value": "curiosity", "importance": 0.8},
+		}})
+	}},
+	{"demographic", func(string) string {
+		return mustJson(map[string]any{
+			"age_range": "25-34", "gender": "unknown", "location": "Boston, MA", "occupation": "software engineer",
+			"highest_education": "bachelor's degree", "living_status": "with roommates", "political_affiliation": "independent",
+			"religious_affiliation": "none", "nationality": "American", "spoken_languages": []string{"English"},
+			"social_class": "middle class",
+		})
+	}},
+	{"'lived_experiences'", func(string) string {
+		return mustJson(map[string]any{"lived_experiences": []string{"moved to a new city", "started a first job"}})
+	}},
+	{"'habits'", func(string) string {
+		return mustJson(map[string]any{"habits": []string{"morning coffee", "evening walks"}})
+	}},
+	{"'hobbies'", func(string) string {
+		return mustJson(map[string]any{"hobbies": []string{"playing board games", "hiking trails"}})
+	}},
+	{"'exceptional_circumstances'", func(string) string {
+		return mustJson(map[string]any{"analysis": "nothing mentioned", "exceptional_circumstances": []string{}})
+	}},
+	{"'topics'", func(string) string {
+		return mustJson(map[string]any{"topics": []map[string]any{
+			{"topic": "weekend plans", "level": 0.6, "emoji": "📅"},
+		}})
+	}},
+	{"'bio'", func(string) string {
+		return mustJson(map[string]string{"bio": "I'm a curious engineer who loves board games and long hikes."})
+	}},
+	{"'key_questions'", func(string) string {
+		return mustJson(map[string]any{"key_questions": []string{"What's a good two-player board game?"}})
+	}},
+	{"'tags'", func(string) string {
+		return mustJson(map[string]any{"tags": []map[string]string{
+			{"tag": "analytical thinker", "emoji": "🧠"},
+			{"tag": "trail explorer", "emoji": "🌲"},
+		}})
+	}},
+	{"'summary'", func(string) string {
+		return mustJson(map[string]string{"summary": "A curious software engineer who enjoys board games and hiking."})
+	}},
+	{"'subtitle'", func(string) string {
+		return mustJson(map[string]string{"subtitle": "Dice roller, trail wanderer"})
+	}},
+	{"'looking_for'", func(string) string {
+		return mustJson(map[string]string{"looking_for": "Friends to share game nights and weekend hikes"})
+	}},
+}
+
+func candidateIds(prompt string) []string {
+	type candidate struct {
+		Id string `json:"id"`
+	}
+	data := struct {
+		Users      []candidate `json:"users"`
+		Candidates []candidate `json:"candidates"`
+	}{}
+	json.Unmarshal([]byte(prompt), &data)
+
+	ids := []string{}
+	for _, user := range append(data.Users, data.Candidates...) {
+		ids = append(ids, user.Id)
+	}
+
+	return ids
+}
+
+func candidateMatches(prompt string) string {
+	ids := candidateIds(prompt)
+	if len(ids) > 4 {
+		ids = ids[:4]
+	}
+
+	matches := make([]map[string]string, len(ids))
+	for i, id := range ids {
+		matches[i] = map[string]string{"id": id, "reason": "They share several interests and speak a common language."}
+	}
+
+	return mustJson(map[string]any{"matches": matches})
+}
+
+func bestMatch(prompt string) string {
+	explanations := map[string]string{}
+	json.Unmarshal([]byte(prompt), &explanations)
+
+	ids := make([]string, 0, len(explanations))
+	for id := range explanations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	best, tied := "", []string{}
+	if len(ids) > 0 {
+		best, tied = ids[0], ids[1:]
+	}
+
+	return mustJson(map[string]any{
+		"reasoning":  "Every candidate has something in common with the user and none stands out, so they are all listed as equally good.",
+		"best_match": best,
+		"tied_with":  tied,
+	})
+}
+
+const toolProtocol = `To call a tool, reply with {"tool":`
+
+func reply(script []rule, system, prompt string) string {
+	if strings.Contains(system, toolProtocol) {
+		return respondWithTools(script, system, prompt)
+	}
+
+	return respond(script, system, prompt)
+}
+
+// Tool-calling requests get one profile lookup before the scripted answer is
+// wrapped as the final answer.
+func respondWithTools(script []rule, system, prompt string) string {
+	first, _, multiTurn := strings.Cut(strings.TrimPrefix(prompt, "User: "), "\n\nAssistant: ")
+	if !multiTurn {
+		if ids := candidateIds(first); len(ids) > 0 && strings.Contains(system, "lookup_user_profile") {
+			return mustJson(map[string]any{"tool": "lookup_user_profile", "arguments": map[string]string{"id": ids[0]}})
+		}
+	}
+
+	response := respond(script, system, first)
+
+	var answer any
+	if err := json.Unmarshal([]byte(response), &answer); err != nil {
+		return response
+	}
+
+	return mustJson(map[string]any{"answer": answer})
+}
+
+func respond(script []rule, system, prompt string) string {
+	for _, rule := range script {
+		if strings.Contains(system, rule.Match) {
+			return rule.Response
+		}
+	}
+
+	for _, rule := range builtinRules {
+		if strings.Contains(system, rule.match) {
+			return rule.respond(prompt)
+		}
+	}
+
+	return "{}"
+}
@@ -0,0 +1,601 @@
+package compatibility
+
+// This is Edmonds' blossom algorithm for maximum weight matching in general
+// graphs, in the O(n³) primal-dual form described by Galil ("Efficient
+// algorithms for finding maximum matching in graphs", 1986) and following
+// Joris van Rantwijk's reference implementation. Weights are integers so the
+// dual variables stay exact.
+
+type weightedEdge struct {
+	i, j   int
+	weight int64
+}
+
+type blossomMatcher struct {
+	edges    []weightedEdge
+	vertices int
+
+	endpoint  []int
+	neighbend [][]int
+
+	mate             []int
+	label            []int
+	labelend         []int
+	inblossom        []int
+	blossomparent    []int
+	blossomchilds    [][]int
+	blossombase      []int
+	blossomendps     [][]int
+	bestedge         []int
+	blossombestedges [][]int
+	unusedblossoms   []int
+	dualvar          []int64
+	allowedge        []bool
+	queue            []int
+}
+
+// maxWeightMatching returns the partner of every vertex in a matching of
+// maximum total weight, or -1 for unmatched vertices. Edges must not repeat a
+// pair of vertices.
+func maxWeightMatching(vertices int, edges []weightedEdge) []int {
+	if len(edges) == 0 {
+		mate := make([]int, vertices)
+		for i := range mate {
+			mate[i] = -1
+		}
+		return mate
+	}
+
+	m := &blossomMatcher{edges: edges, vertices: vertices}
+	m.init()
+	m.solve()
+
+	mate := make([]int, vertices)
+	for v := range mate {
+		mate[v] = -1
+		if m.mate[v] >= 0 {
+			mate[v] = m.endpoint[m.mate[v]]
+		}
+	}
+
+	return mate
+}
+
+func (m *blossomMatcher) init() {
+	n := m.vertices
+
+	var maxWeight int64
+	for _, e := range m.edges {
+		maxWeight = max(maxWeight, e.weight)
+	}
+
+	m.endpoint = make([]int, 2*len(m.edges))
+	m.neighbend = make([][]int, n)
+	for k, e := range m.edges {
+		m.endpoint[2*k] = e.i
+		m.endpoint[2*k+1] = e.j
+		m.neighbend[e.i] = append(m.neighbend[e.i], 2*k+1)
+		m.neighbend[e.j] = append(m.neighbend[e.j], 2*k)
+	}
+
+	m.mate = filled(n, -1)
+	m.label = make([]int, 2*n)
+	m.labelend = filled(2*n, -1)
+	m.inblossom = make([]int, n)
+	m.blossomparent = filled(2*n, -1)
+	m.blossomchilds = make([][]int, 2*n)
+	m.blossombase = filled(2*n, -1)
+	m.blossomendps = make([][]int, 2*n)
+	m.bestedge = filled(2*n, -1)
+	m.blossombestedges = make([][]int, 2*n)
+	m.dualvar = make([]int64, 2*n)
+	m.allowedge = make([]bool, len(m.edges))
+
+	for v := 0; v < n; v++ {
+		m.inblossom[v] = v
+		m.blossombase[v] = v
+		m.dualvar[v] = maxWeight
+		m.unusedblossoms = append(m.unusedblossoms, n+v)
+	}
+}
+
+func filled(length, value int) []int {
+	values := make([]int, length)
+	for i := range values {
+		values[i] = value
+	}
+	return values
+}
+
+// at indexes values from the end for negative i.
+func at(values []int, i int) int {
+	if i < 0 {
+		i += len(values)
+	}
+	return values[i]
+}
+
+func indexOf(values []int, value int) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *blossomMatcher) slack(k int) int64 {
+	e := m.edges[k]
+	return m.dualvar[e.i] + m.dualvar[e.j] - 2*e.weight
+}
+
+func (m *blossomMatcher) leaves(b int) []int {
+	if b < m.vertices {
+		return []int{b}
+	}
+
+	leaves := []int{}
+	for _, t := range m.blossomchilds[b] {
+		leaves = append(leaves, m.leaves(t)...)
+	}
+	return leaves
+}
+
+// assignLabel labels the top-level blossom containing w with t (1 for S, 2
+// for T), reached through endpoint p.
+func (m *blossomMatcher) assignLabel(w, t, p int) {
+	b := m.inblossom[w]
+	m.label[w], m.label[b] = t, t
+	m.labelend[w], m.labelend[b] = p, p
+	m.bestedge[w], m.bestedge[b] = -1, -1
+
+	if t == 1 {
+		m.queue = append(m.queue, m.leaves(b)...)
+	} else if t == 2 {
+		base := m.blossombase[b]
+		m.assignLabel(m.endpoint[m.mate[base]], 1, m.mate[base]^1)
+	}
+}
+
+// scanBlossom traces back from v and w to find the base of a new blossom, or
+// returns -1 if they lead to different roots and an augmenting path exists.
+func (m *blossomMatcher) scanBlossom(v, w int) int {
+	path := []int{}
+	base := -1
+	for v != -1 || w != -1 {
+		b := m.inblossom[v]
+		if m.label[b]&4 != 0 {
+			base = m.blossombase[b]
+			break
+		}
+
+		path = append(path, b)
+		m.label[b] = 5
+		if m.labelend[b] == -1 {
+			v = -1
+		} else {
+			v = m.endpoint[m.labelend[b]]
+			b = m.inblossom[v]
+			v = m.endpoint[m.labelend[b]]
+		}
+
+		if w != -1 {
+			v, w = w, v
+		}
+	}
+
+	for _, b := range path {
+		m.label[b] = 1
+	}
+
+	return base
+}
+
+func (m *blossomMatcher) addBlossom(base, k int) {
+	v, w := m.edges[k].i, m.edges[k].j
+	bb := m.inblossom[base]
+	bv := m.inblossom[v]
+	bw := m.inblossom[w]
+
+	b := m.unusedblossoms[len(m.unusedblossoms)-1]
+	m.unusedblossoms = m.unusedblossoms[:len(m.unusedblossoms)-1]
+	m.blossombase[b] = base
+	m.blossomparent[b] = -1
+	m.blossomparent[bb] = b
+
+	path := []int{}
+	endps := []int{}
+	for bv != bb {
+		m.blossomparent[bv] = b
+		path = append(path, bv)
+		endps = append(endps, m.labelend[bv])
+		v = m.endpoint[m.labelend[bv]]
+		bv = m.inblossom[v]
+	}
+	path = append(path, bb)
+	reverse(path)
+	reverse(endps)
+	endps = append(endps, 2*k)
+
+	for bw != bb {
+		m.blossomparent[bw] = b
+		path = append(path, bw)
+		endps = append(endps, m.labelend[bw]^1)
+		w = m.endpoint[m.labelend[bw]]
+		bw = m.inblossom[w]
+	}
+
+	m.blossomchilds[b] = path
+	m.blossomendps[b] = endps
+	m.label[b] = 1
+	m.labelend[b] = m.labelend[bb]
+	m.dualvar[b] = 0
+
+	for _, v := range m.leaves(b) {
+		if m.label[m.inblossom[v]] == 2 {
+			m.queue = append(m.queue, v)
+		}
+		m.inblossom[v] = b
+	}
+
+	bestedgeto := filled(2*m.vertices, -1)
+	for _, bv := range path {
+		var lists [][]int
+		if m.blossombestedges[bv] == nil {
+			for _, v := range m.leaves(bv) {
+				list := make([]int, len(m.neighbend[v]))
+				for i, p := range m.neighbend[v] {
+					list[i] = p / 2
+				}
+				lists = append(lists, list)
+			}
+		} else {
+			lists = [][]int{m.blossombestedges[bv]}
+		}
+
+		for _, list := range lists {
+			for _, k := range list {
+				j := m.edges[k].j
+				if m.inblossom[j] == b {
+					j = m.edges[k].i
+				}
+				bj := m.inblossom[j]
+				if bj != b && m.label[bj] == 1 && (bestedgeto[bj] == -1 || m.slack(k) < m.slack(bestedgeto[bj])) {
+					bestedgeto[bj] = k
+				}
+			}
+		}
+
+		m.blossombestedges[bv] = nil
+		m.bestedge[bv] = -1
+	}
+
+	best := []int{}
+	for _, k := range bestedgeto {
+		if k != -1 {
+			best = append(best, k)
+		}
+	}
+	m.blossombestedges[b] = best
+
+	m.bestedge[b] = -1
+	for _, k := range best {
+		if m.bestedge[b] == -1 || m.slack(k) < m.slack(m.bestedge[b]) {
+			m.bestedge[b] = k
+		}
+	}
+}
+
+func reverse(values []int) {
+	for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+		values[i], values[j] = values[j], values[i]
+	}
+}
+
+func (m *blossomMatcher) expandBlossom(b int, endstage bool) {
+	for _, s := range m.blossomchilds[b] {
+		m.blossomparent[s] = -1
+		if s < m.vertices {
+			m.inblossom[s] = s
+		} else if endstage && m.dualvar[s] == 0 {
+			m.expandBlossom(s, endstage)
+		} else {
+			for _, v := range m.leaves(s) {
+				m.inblossom[v] = s
+			}
+		}
+	}
+
+	if !endstage && m.label[b] == 2 {
+		childs := m.blossomchilds[b]
+		endps := m.blossomendps[b]
+
+		entrychild := m.inblossom[m.endpoint[m.labelend[b]^1]]
+		j := indexOf(childs, entrychild)
+		var jstep, endptrick int
+		if j&1 != 0 {
+			j -= len(childs)
+			jstep = 1
+		} else {
+			jstep = -1
+			endptrick = 1
+		}
+
+		p := m.labelend[b]
+		for j != 0 {
+			m.label[m.endpoint[p^1]] = 0
+			m.label[m.endpoint[at(endps, j-endptrick)^endptrick^1]] = 0
+			m.assignLabel(m.endpoint[p^1], 2, p)
+			m.allowedge[at(endps, j-endptrick)/2] = true
+			j += jstep
+			p = at(endps, j-endptrick) ^ endptrick
+			m.allowedge[p/2] = true
+			j += jstep
+		}
+
+		bv := at(childs, j)
+		m.label[m.endpoint[p^1]], m.label[bv] = 2, 2
+		m.labelend[m.endpoint[p^1]], m.labelend[bv] = p, p
+		m.bestedge[bv] = -1
+		j += jstep
+
+		for at(childs, j) != entrychild {
+			bv := at(childs, j)
+			if m.label[bv] == 1 {
+				j += jstep
+				continue
+			}
+
+			labelled := -1
+			for _, v := range m.leaves(bv) {
+				if m.label[v] != 0 {
+					labelled = v
+					break
+				}
+			}
+			if labelled != -1 {
+				m.label[labelled] = 0
+				m.label[m.endpoint[m.mate[m.blossombase[bv]]]] = 0
+				m.assignLabel(labelled, 2, m.labelend[labelled])
+			}
+			j += jstep
+		}
+	}
+
+	m.label[b], m.labelend[b] = -1, -1
+	m.blossomchilds[b], m.blossomendps[b] = nil, nil
+	m.blossombase[b] = -1
+	m.blossombestedges[b] = nil
+	m.bestedge[b] = -1
+	m.unusedblossoms = append(m.unusedblossoms, b)
+}
+
+// augmentBlossom swaps matched and unmatched edges along the even path
+// through blossom b from vertex v to its base, making v the new base.
+func (m *blossomMatcher) augmentBlossom(b, v int) {
+	t := v
+	for m.blossomparent[t] != b {
+		t = m.blossomparent[t]
+	}
+	if t >= m.vertices {
+		m.augmentBlossom(t, v)
+	}
+
+	childs := m.blossomchilds[b]
+	endps := m.blossomendps[b]
+
+	i := indexOf(childs, t)
+	j := i
+	var jstep, endptrick int
+	if i&1 != 0 {
+		j -= len(childs)
+		jstep = 1
+	} else {
+		jstep = -1
+		endptrick = 1
+	}
+
+	for j != 0 {
+		j += jstep
+		t = at(childs, j)
+		p := at(endps, j-endptrick) ^ endptrick
+		if t >= m.vertices {
+			m.augmentBlossom(t, m.endpoint[p])
+		}
+		j += jstep
+		t = at(childs, j)
+		if t >= m.vertices {
+			m.augmentBlossom(t, m.endpoint[p^1])
+		}
+		m.mate[m.endpoint[p]] = p ^ 1
+		m.mate[m.endpoint[p^1]] = p
+	}
+
+	m.blossomchilds[b] = append(append([]int{}, childs[i:]...), childs[:i]...)
+	m.blossomendps[b] = append(append([]int{}, endps[i:]...), endps[:i]...)
+	m.blossombase[b] = m.blossombase[m.blossomchilds[b][0]]
+}
+
+// augmentMatching flips the augmenting path through edge k.
+func (m *blossomMatcher) augmentMatching(k int) {
+	v, w := m.edges[k].i, m.edges[k].j
+	for _, start := range [][2]int{{v, 2*k + 1}, {w, 2 * k}} {
+		s, p := start[0], start[1]
+		for {
+			bs := m.inblossom[s]
+			if bs >= m.vertices {
+				m.augmentBlossom(bs, s)
+			}
+			m.mate[s] = p
+			if m.labelend[bs] == -1 {
+				break
+			}
+
+			t := m.endpoint[m.labelend[bs]]
+			bt := m.inblossom[t]
+			s = m.endpoint[m.labelend[bt]]
+			j := m.endpoint[m.labelend[bt]^1]
+			if bt >= m.vertices {
+				m.augmentBlossom(bt, j)
+			}
+			m.mate[j] = m.labelend[bt]
+			p = m.labelend[bt] ^ 1
+		}
+	}
+}
+
+func (m *blossomMatcher) solve() {
+	n := m.vertices
+
+	for stage := 0; stage < n; stage++ {
+		for i := range m.label {
+			m.label[i] = 0
+			m.bestedge[i] = -1
+		}
+		for b := n; b < 2*n; b++ {
+			m.blossombestedges[b] = nil
+		}
+		for k := range m.allowedge {
+			m.allowedge[k] = false
+		}
+		m.queue = m.queue[:0]
+
+		for v := 0; v < n; v++ {
+			if m.mate[v] == -1 && m.label[m.inblossom[v]] == 0 {
+				m.assignLabel(v, 1, -1)
+			}
+		}
+
+		augmented := false
+		for {
+			for len(m.queue) > 0 && !augmented {
+				v := m.queue[len(m.queue)-1]
+				m.queue = m.queue[:len(m.queue)-1]
+
+				for _, p := range m.neighbend[v] {
+					k := p / 2
+					w := m.endpoint[p]
+					if m.inblossom[v] == m.inblossom[w] {
+						continue
+					}
+
+					var kslack int64
+					if !m.allowedge[k] {
+						kslack = m.slack(k)
+						if kslack <= 0 {
+							m.allowedge[k] = true
+						}
+					}
+
+					if m.allowedge[k] {
+						if m.label[m.inblossom[w]] == 0 {
+							m.assignLabel(w, 2, p^1)
+						} else if m.label[m.inblossom[w]] == 1 {
+							base := m.scanBlossom(v, w)
+							if base >= 0 {
+								m.addBlossom(base, k)
+							} else {
+								m.augmentMatching(k)
+								augmented = true
+								break
+							}
+						} else if m.label[w] == 0 {
+							m.label[w] = 2
+							m.labelend[w] = p ^ 1
+						}
+					} else if m.label[m.inblossom[w]] == 1 {
+						b := m.inblossom[v]
+						if m.bestedge[b] == -1 || kslack < m.slack(m.bestedge[b]) {
+							m.bestedge[b] = k
+						}
+					} else if m.label[w] == 0 {
+						if m.bestedge[w] == -1 || kslack < m.slack(m.bestedge[w]) {
+							m.bestedge[w] = k
+						}
+					}
+				}
+			}
+
+			if augmented {
+				break
+			}
+
+			deltatype := 1
+			delta := m.dualvar[0]
+			for v := 1; v < n; v++ {
+				delta = min(delta, m.dualvar[v])
+			}
+			deltaedge, deltablossom := -1, -1
+
+			for v := 0; v < n; v++ {
+				if m.label[m.inblossom[v]] == 0 && m.bestedge[v] != -1 {
+					if d := m.slack(m.bestedge[v]); d < delta {
+						delta, deltatype, deltaedge = d, 2, m.bestedge[v]
+					}
+				}
+			}
+
+			for b := 0; b < 2*n; b++ {
+				if m.blossomparent[b] == -1 && m.label[b] == 1 && m.bestedge[b] != -1 {
+					if d := m.slack(m.bestedge[b]) / 2; d < delta {
+						delta, deltatype, deltaedge = d, 3, m.bestedge[b]
+					}
+				}
+			}
+
+			for b := n; b < 2*n; b++ {
+				if m.blossombase[b] >= 0 && m.blossomparent[b] == -1 && m.label[b] == 2 && m.dualvar[b] < delta {
+					delta, deltatype, deltablossom = m.dualvar[b], 4, b
+				}
+			}
+
+			for v := 0; v < n; v++ {
+				switch m.label[m.inblossom[v]] {
+				case 1:
+					m.dualvar[v] -= delta
+				case 2:
+					m.dualvar[v] += delta
+				}
+			}
+			for b := n; b < 2*n; b++ {
+				if m.blossombase[b] >= 0 && m.blossomparent[b] == -1 {
+					switch m.label[b] {
+					case 1:
+						m.dualvar[b] += delta
+					case 2:
+						m.dualvar[b] -= delta
+					}
+				}
+			}
+
+			if deltatype == 1 {
+				break
+			}
+
+			switch deltatype {
+			case 2:
+				m.allowedge[deltaedge] = true
+				i := m.edges[deltaedge].i
+				if m.label[m.inblossom[i]] == 0 {
+					i = m.edges[deltaedge].j
+				}
+				m.queue = append(m.queue, i)
+			case 3:
+				m.allowedge[deltaedge] = true
+				m.queue = append(m.queue, m.edges[deltaedge].i)
+			case 4:
+				m.expandBlossom(deltablossom, false)
+			}
+		}
+
+		if !augmented {
+			break
+		}
+
+		for b := n; b < 2*n; b++ {
+			if m.blossomparent[b] == -1 && m.blossombase[b] >= 0 && m.label[b] == 1 && m.dualvar[b] == 0 {
+				m.expandBlossom(b, true)
+			}
+		}
+	}
+}
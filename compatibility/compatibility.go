@@ -0,0 +1,281 @@
+package compatibility
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+// Weights of each sub-score in the overall score.
+type Weights struct {
+	Interests   float64
+	Topics      float64
+	Personality float64
+	Languages   float64
+	Values      float64
+	Goals       float64
+}
+
+var DefaultWeights = Weights{
+	Interests:   0.25,
+	Topics:      0.15,
+	Personality: 0.2,
+	Languages:   0.1,
+	Values:      0.15,
+	Goals:       0.15,
+}
+
+// Users scoring below MinimumScore, or sharing no language, are dropped by
+// Prefilter.
+const MinimumScore = 0.15
+
+const personalityScale = 5
+
+type weightedText struct {
+	text   string
+	weight float64
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "the": true, "of": true, "to": true, "in": true,
+	"for": true, "with": true, "on": true, "at": true, "my": true, "be": true, "is": true,
+}
+
+func tokens(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	result := words[:0]
+	for _, word := range words {
+		if !stopWords[word] {
+			result = append(result, word)
+		}
+	}
+
+	return result
+}
+
+// overlap is the weighted Jaccard similarity of the words in two lists, so
+// "board games" and "video games" partially match and strongly held items
+// count for more.
+func overlap(a, b []weightedText) float64 {
+	weights := func(items []weightedText) map[string]float64 {
+		result := map[string]float64{}
+		for _, item := range items {
+			for _, token := range tokens(item.text) {
+				result[token] = math.Max(result[token], item.weight)
+			}
+		}
+		return result
+	}
+
+	aWeights, bWeights := weights(a), weights(b)
+
+	var intersection, union float64
+	for token, aWeight := range aWeights {
+		bWeight := bWeights[token]
+		intersection += math.Min(aWeight, bWeight)
+		union += math.Max(aWeight, bWeight)
+	}
+	for token, bWeight := range bWeights {
+		if _, ok := aWeights[token]; !ok {
+			union += bWeight
+		}
+	}
+
+	if union == 0 {
+		return 0
+	}
+
+	return intersection / union
+}
+
+func interests(profile *model.InternalProfile) []weightedText {
+	items := make([]weightedText, len(profile.Interests))
+	for i, interest := range profile.Interests {
+		items[i] = weightedText{interest.Interest, interest.Level}
+	}
+
+	return items
+}
+
+func topics(profile *model.InternalProfile) []weightedText {
+	items := make([]weightedText, len(profile.Topics))
+	for i, topic := range profile.Topics {
+		items[i] = weightedText{topic.Topic, topic.Level}
+	}
+
+	return items
+}
+
+func values(profile *model.InternalProfile) []weightedText {
+	items := make([]weightedText, len(profile.Values))
+	for i, value := range profile.Values {
+		items[i] = weightedText{value.Value, value.Importance}
+	}
+
+	return items
+}
+
+func goals(profile *model.InternalProfile) []weightedText {
+	items := make([]weightedText, len(profile.Goals))
+	for i, goal := range profile.Goals {
+		items[i] = weightedText{goal.Goal, goal.Importance}
+	}
+
+	return items
+}
+
+func similar(a, b float64) float64 {
+	return 1 - math.Abs(a-b)/personalityScale
+}
+
+// personality rewards similar openness, agreeableness and conscientiousness,
+// an introvert/extrovert balance that lands near the middle, and low
+// combined neuroticism.
+func personality(a, b model.Personality) float64 {
+	openness := similar(a.Openness, b.Openness)
+	agreeableness := similar(a.Agreeableness, b.Agreeableness)
+	conscientiousness := similar(a.Conscientiousness, b.Conscientiousness)
+	extroversion := 1 - math.Abs(a.Extroversion+b.Extroversion-personalityScale)/personalityScale
+	stability := 1 - (a.Neuroticism+b.Neuroticism)/(2*personalityScale)
+
+	return clamp((openness + agreeableness + conscientiousness + extroversion + stability) / 5)
+}
+
+func languages(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	spoken := map[string]bool{}
+	for _, language := range a {
+		spoken[strings.ToLower(strings.TrimSpace(language))] = true
+	}
+
+	shared := 0
+	for _, language := range b {
+		if spoken[strings.ToLower(strings.TrimSpace(language))] {
+			shared++
+		}
+	}
+
+	return clamp(float64(shared) / float64(min(len(a), len(b))))
+}
+
+func clamp(value float64) float64 {
+	return math.Max(0, math.Min(1, value))
+}
+
+func Score(a, b *model.InternalProfile) model.CompatibilityScores {
+	return ScoreWeighted(a, b, DefaultWeights)
+}
+
+func ScoreWeighted(a, b *model.InternalProfile, weights Weights) model.CompatibilityScores {
+	scores := model.CompatibilityScores{
+		Interests:   overlap(interests(a), interests(b)),
+		Topics:      overlap(topics(a), topics(b)),
+		Personality: personality(a.Personality, b.Personality),
+		Languages:   languages(a.Demographics.SpokenLanguages, b.Demographics.SpokenLanguages),
+		Values:      overlap(values(a), values(b)),
+		Goals:       overlap(goals(a), goals(b)),
+	}
+
+	total := weights.Interests + weights.Topics + weights.Personality + weights.Languages + weights.Values + weights.Goals
+	if total > 0 {
+		scores.Overall = (weights.Interests*scores.Interests +
+			weights.Topics*scores.Topics +
+			weights.Personality*scores.Personality +
+			weights.Languages*scores.Languages +
+			weights.Values*scores.Values +
+			weights.Goals*scores.Goals) / total
+	}
+
+	return scores
+}
+
+const maxSharedInterests = 5
+
+// SharedInterests returns a's interests that share a word with one of b's,
+// strongest first.
+func SharedInterests(a, b *model.InternalProfile) []model.Interest {
+	other := map[string]bool{}
+	for _, interest := range b.Interests {
+		for _, token := range tokens(interest.Interest) {
+			other[token] = true
+		}
+	}
+
+	shared := []model.Interest{}
+	for _, interest := range a.Interests {
+		for _, token := range tokens(interest.Interest) {
+			if other[token] {
+				shared = append(shared, interest)
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(shared, func(i, j int) bool {
+		return shared[i].Level > shared[j].Level
+	})
+	if len(shared) > maxSharedInterests {
+		shared = shared[:maxSharedInterests]
+	}
+
+	return shared
+}
+
+type Ranked struct {
+	User   model.User
+	Scores model.CompatibilityScores
+}
+
+// Rank scores every user with a profile against user, best first.
+func Rank(user model.User, users []model.User) []Ranked {
+	return RankWeighted(user, users, DefaultWeights)
+}
+
+func RankWeighted(user model.User, users []model.User, weights Weights) []Ranked {
+	ranked := []Ranked{}
+	for _, other := range users {
+		if other.Profile == nil {
+			continue
+		}
+		ranked = append(ranked, Ranked{other, ScoreWeighted(user.Profile, other.Profile, weights)})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Scores.Overall > ranked[j].Scores.Overall
+	})
+
+	return ranked
+}
+
+// Prefilter drops users who share no language with user or score below
+// MinimumScore, keeping the pool unchanged if fewer than keep would remain.
+func Prefilter(user model.User, users []model.User, keep int) []model.User {
+	return PrefilterWeighted(user, users, keep, DefaultWeights)
+}
+
+// PrefilterWeighted is Prefilter with the overall score computed from weights,
+// so the surviving users are also ordered by them.
+func PrefilterWeighted(user model.User, users []model.User, keep int, weights Weights) []model.User {
+	filtered := []model.User{}
+	for _, ranked := range RankWeighted(user, users, weights) {
+		if ranked.Scores.Languages == 0 || ranked.Scores.Overall < MinimumScore {
+			continue
+		}
+		filtered = append(filtered, ranked.User)
+	}
+
+	if len(filtered) < keep {
+		return users
+	}
+
+	return filtered
+}
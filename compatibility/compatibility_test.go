@@ -0,0 +1,164 @@
+package compatibility
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+func testProfile(languages []string, interests ...model.Interest) *model.InternalProfile {
+	return &model.InternalProfile{
+		Interests:    interests,
+		Topics:       []model.Topic{{Topic: "travel", Level: 1}},
+		Values:       []model.CoreValue{{Value: "honesty", Importance: 1}},
+		Goals:        []model.Goal{{Goal: "run a marathon", Importance: 1}},
+		Personality:  model.Personality{Openness: 3, Conscientiousness: 3, Extroversion: 2.5, Agreeableness: 3, Neuroticism: 0},
+		Demographics: model.Demographics{SpokenLanguages: languages},
+	}
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestScoreIdentical(t *testing.T) {
+	profile := testProfile([]string{"English"}, model.Interest{Interest: "board games", Level: 0.8})
+
+	scores := Score(profile, profile)
+	want := model.CompatibilityScores{Interests: 1, Topics: 1, Personality: 1, Languages: 1, Values: 1, Goals: 1, Overall: 1}
+	for name, got := range map[string][2]float64{
+		"interests":   {scores.Interests, want.Interests},
+		"topics":      {scores.Topics, want.Topics},
+		"personality": {scores.Personality, want.Personality},
+		"languages":   {scores.Languages, want.Languages},
+		"values":      {scores.Values, want.Values},
+		"goals":       {scores.Goals, want.Goals},
+		"overall":     {scores.Overall, want.Overall},
+	} {
+		if !approx(got[0], got[1]) {
+			t.Errorf("%s: got %f, want %f", name, got[0], got[1])
+		}
+	}
+}
+
+func TestScoreDisjoint(t *testing.T) {
+	a := testProfile([]string{"English"}, model.Interest{Interest: "chess", Level: 1})
+	b := &model.InternalProfile{
+		Interests:    []model.Interest{{Interest: "surfing", Level: 1}},
+		Topics:       []model.Topic{{Topic: "cooking", Level: 1}},
+		Values:       []model.CoreValue{{Value: "ambition", Importance: 1}},
+		Goals:        []model.Goal{{Goal: "learn piano", Importance: 1}},
+		Demographics: model.Demographics{SpokenLanguages: []string{"French"}},
+	}
+
+	scores := Score(a, b)
+	for name, got := range map[string]float64{
+		"interests": scores.Interests,
+		"topics":    scores.Topics,
+		"languages": scores.Languages,
+		"values":    scores.Values,
+		"goals":     scores.Goals,
+	} {
+		if got != 0 {
+			t.Errorf("%s: got %f, want 0", name, got)
+		}
+	}
+	if !approx(scores.Overall, DefaultWeights.Personality*scores.Personality) {
+		t.Errorf("got overall %f, want only the personality share", scores.Overall)
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b []weightedText
+		want float64
+	}{
+		{"nil", nil, nil, 0},
+		{"empty and nil", []weightedText{}, nil, 0},
+		{"one side empty", []weightedText{{"chess", 1}}, nil, 0},
+		{"stop words only", []weightedText{{"the", 1}}, []weightedText{{"the", 1}}, 0},
+		{"shared word", []weightedText{{"board games", 1}}, []weightedText{{"video games", 1}}, 1.0 / 3},
+		{"weaker interest counts for less", []weightedText{{"chess", 0.5}}, []weightedText{{"chess", 1}}, 0.5},
+		{"case and punctuation ignored", []weightedText{{"Rock-Climbing", 1}}, []weightedText{{"rock climbing", 1}}, 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := overlap(tc.a, tc.b); !approx(got, tc.want) {
+				t.Errorf("got %f, want %f", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b []string
+		want float64
+	}{
+		{"nil", nil, []string{"English"}, 0},
+		{"empty", []string{}, []string{}, 0},
+		{"none shared", []string{"English"}, []string{"French"}, 0},
+		{"case and spacing ignored", []string{"English", " Spanish"}, []string{"spanish "}, 1},
+		{"partly shared", []string{"English", "French"}, []string{"English", "German"}, 0.5},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := languages(tc.a, tc.b); !approx(got, tc.want) {
+				t.Errorf("got %f, want %f", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScoreEmptyProfiles(t *testing.T) {
+	scores := Score(&model.InternalProfile{}, &model.InternalProfile{})
+	if scores.Interests != 0 || scores.Languages != 0 || scores.Goals != 0 {
+		t.Errorf("got %+v, want no overlap", scores)
+	}
+	if scores.Overall < 0 || scores.Overall > 1 {
+		t.Errorf("got overall %f outside [0, 1]", scores.Overall)
+	}
+}
+
+func TestScoreWeightedOverall(t *testing.T) {
+	a := testProfile([]string{"English"}, model.Interest{Interest: "board games", Level: 1})
+	b := testProfile([]string{"English", "French"}, model.Interest{Interest: "video games", Level: 1})
+	b.Personality.Neuroticism = 4
+
+	scores := Score(a, b)
+	w := DefaultWeights
+	want := (w.Interests*scores.Interests + w.Topics*scores.Topics + w.Personality*scores.Personality +
+		w.Languages*scores.Languages + w.Values*scores.Values + w.Goals*scores.Goals) /
+		(w.Interests + w.Topics + w.Personality + w.Languages + w.Values + w.Goals)
+	if !approx(scores.Overall, want) {
+		t.Errorf("got overall %f, want %f", scores.Overall, want)
+	}
+
+	interestsOnly := ScoreWeighted(a, b, Weights{Interests: 2})
+	if !approx(interestsOnly.Overall, scores.Interests) {
+		t.Errorf("got overall %f with only interests weighted, want %f", interestsOnly.Overall, scores.Interests)
+	}
+
+	if unweighted := ScoreWeighted(a, b, Weights{}); unweighted.Overall != 0 {
+		t.Errorf("got overall %f with no weights, want 0", unweighted.Overall)
+	}
+}
+
+func TestRankOrdersByOverall(t *testing.T) {
+	user := model.User{Id: "a", Profile: testProfile([]string{"English"}, model.Interest{Interest: "board games", Level: 1})}
+	users := []model.User{
+		{Id: "none", Profile: testProfile([]string{"French"}, model.Interest{Interest: "surfing", Level: 1})},
+		{Id: "same", Profile: testProfile([]string{"English"}, model.Interest{Interest: "board games", Level: 1})},
+		{Id: "no profile"},
+	}
+
+	ranked := Rank(user, users)
+	if len(ranked) != 2 || ranked[0].User.Id != "same" || ranked[1].User.Id != "none" {
+		t.Fatalf("got %+v", ranked)
+	}
+}
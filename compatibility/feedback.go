@@ -0,0 +1,118 @@
+package compatibility
+
+import (
+	"math"
+
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+// A Sample is the compatibility of a past match and how well it went, from 0
+// (declined or ignored) to 1 (rated 5 and chatting).
+type Sample struct {
+	Scores  model.CompatibilityScores
+	Outcome float64
+}
+
+const (
+	// ExplicitWeight is how much a rating counts against engagement when a
+	// match has both.
+	ExplicitWeight = 0.7
+	// EngagedMessageCount is the number of messages at which a conversation
+	// counts as fully engaged.
+	EngagedMessageCount = 20
+	// PriorSamples is how many samples it takes for learned weights to move
+	// halfway from the weights they start from.
+	PriorSamples = 20
+	// minimumWeightShare keeps every feature contributing to the overall score.
+	minimumWeightShare = 0.2
+)
+
+// Engagement scores a conversation by whether both users replied and how many
+// messages they exchanged.
+func Engagement(sent, received int) float64 {
+	replied := 0.0
+	if sent > 0 && received > 0 {
+		replied = 1
+	}
+
+	return 0.5*replied + 0.5*math.Min(1, float64(sent+received)/EngagedMessageCount)
+}
+
+// Outcome combines a 1-5 rating, when there is one, with engagement.
+func Outcome(rating *int, engagement float64) float64 {
+	if rating == nil {
+		return engagement
+	}
+
+	explicit := clamp(float64(*rating-1) / 4)
+	return ExplicitWeight*explicit + (1-ExplicitWeight)*engagement
+}
+
+func (weights Weights) values() [6]float64 {
+	return [6]float64{weights.Interests, weights.Topics, weights.Personality, weights.Languages, weights.Values, weights.Goals}
+}
+
+func weightsOf(values [6]float64) Weights {
+	return Weights{values[0], values[1], values[2], values[3], values[4], values[5]}
+}
+
+func features(scores model.CompatibilityScores) [6]float64 {
+	return [6]float64{scores.Interests, scores.Topics, scores.Personality, scores.Languages, scores.Values, scores.Goals}
+}
+
+// LearnWeights adjusts base by how strongly each feature correlated with
+// good outcomes in samples. A feature whose score tracks outcomes perfectly
+// has its weight doubled and one that tracks them inversely is cut to a fifth,
+// with the change shrunk towards base while there are few samples. The
+// learned weights are scaled to the same total as base.
+func LearnWeights(base Weights, samples []Sample) Weights {
+	if len(samples) < 2 {
+		return base
+	}
+
+	n := float64(len(samples))
+	var meanOutcome float64
+	var means [6]float64
+	for _, sample := range samples {
+		meanOutcome += sample.Outcome / n
+		for f, value := range features(sample.Scores) {
+			means[f] += value / n
+		}
+	}
+
+	var outcomeVariance float64
+	var covariance, variance [6]float64
+	for _, sample := range samples {
+		dy := sample.Outcome - meanOutcome
+		outcomeVariance += dy * dy
+		for f, value := range features(sample.Scores) {
+			dx := value - means[f]
+			covariance[f] += dx * dy
+			variance[f] += dx * dx
+		}
+	}
+
+	confidence := n / (n + PriorSamples)
+	learned := base.values()
+	for f := range learned {
+		if variance[f] == 0 || outcomeVariance == 0 {
+			continue
+		}
+
+		correlation := covariance[f] / math.Sqrt(variance[f]*outcomeVariance)
+		learned[f] *= math.Max(minimumWeightShare, 1+confidence*correlation)
+	}
+
+	var baseTotal, learnedTotal float64
+	for f, value := range base.values() {
+		baseTotal += value
+		learnedTotal += learned[f]
+	}
+	if learnedTotal > 0 {
+		for f := range learned {
+			learned[f] *= baseTotal / learnedTotal
+		}
+	}
+
+	return weightsOf(learned)
+}
@@ -0,0 +1,111 @@
+package compatibility
+
+import (
+	"testing"
+
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+func total(weights Weights) float64 {
+	var sum float64
+	for _, value := range weights.values() {
+		sum += value
+	}
+	return sum
+}
+
+func TestLearnWeightsWithoutFeedback(t *testing.T) {
+	for _, samples := range [][]Sample{nil, {}, {{Scores: model.CompatibilityScores{Interests: 1}, Outcome: 1}}} {
+		if got := LearnWeights(DefaultWeights, samples); got != DefaultWeights {
+			t.Errorf("got %+v from %d samples, want the defaults", got, len(samples))
+		}
+	}
+}
+
+func TestLearnWeightsAllPositive(t *testing.T) {
+	samples := []Sample{
+		{Scores: model.CompatibilityScores{Interests: 0.9, Goals: 0.1}, Outcome: 1},
+		{Scores: model.CompatibilityScores{Interests: 0.2, Goals: 0.8}, Outcome: 1},
+		{Scores: model.CompatibilityScores{Interests: 0.5, Goals: 0.5}, Outcome: 1},
+	}
+
+	got := LearnWeights(DefaultWeights, samples)
+	if got != DefaultWeights {
+		t.Errorf("got %+v, want the defaults when outcomes do not vary", got)
+	}
+	if !approx(total(got), total(DefaultWeights)) {
+		t.Errorf("weights sum to %f, want %f", total(got), total(DefaultWeights))
+	}
+}
+
+func TestLearnWeightsMixed(t *testing.T) {
+	var samples []Sample
+	for i := 0; i < 40; i++ {
+		good := i%2 == 0
+		scores := model.CompatibilityScores{Interests: 0.2, Goals: 0.8, Personality: 0.5}
+		outcome := 0.1
+		if good {
+			scores.Interests, scores.Goals = 0.9, 0.1
+			outcome = 0.9
+		}
+		samples = append(samples, Sample{Scores: scores, Outcome: outcome})
+	}
+
+	got := LearnWeights(DefaultWeights, samples)
+
+	if !approx(total(got), total(DefaultWeights)) {
+		t.Errorf("weights sum to %f, want %f", total(got), total(DefaultWeights))
+	}
+	if got.Interests <= DefaultWeights.Interests {
+		t.Errorf("interests weight %f did not grow from %f although they predicted good matches", got.Interests, DefaultWeights.Interests)
+	}
+	if got.Goals >= DefaultWeights.Goals {
+		t.Errorf("goals weight %f did not shrink from %f although they predicted bad matches", got.Goals, DefaultWeights.Goals)
+	}
+	for f, value := range got.values() {
+		if value <= 0 {
+			t.Errorf("feature %d lost all weight: %+v", f, got)
+		}
+	}
+
+	// Features that never varied keep their share relative to each other.
+	if !approx(got.Topics/got.Values, DefaultWeights.Topics/DefaultWeights.Values) {
+		t.Errorf("got topics/values ratio %f, want %f", got.Topics/got.Values, DefaultWeights.Topics/DefaultWeights.Values)
+	}
+}
+
+func TestLearnWeightsClampsInverseFeatures(t *testing.T) {
+	var samples []Sample
+	for i := 0; i < 1000; i++ {
+		interests := float64(i % 2)
+		samples = append(samples, Sample{Scores: model.CompatibilityScores{Interests: interests}, Outcome: 1 - interests})
+	}
+
+	got := LearnWeights(DefaultWeights, samples)
+	if !approx(total(got), total(DefaultWeights)) {
+		t.Errorf("weights sum to %f, want %f", total(got), total(DefaultWeights))
+	}
+
+	// Interests track outcomes perfectly inversely, so they keep about a
+	// fifth of their share relative to the unchanged features.
+	ratio := (got.Interests / got.Topics) / (DefaultWeights.Interests / DefaultWeights.Topics)
+	if ratio < minimumWeightShare-1e-9 || ratio > minimumWeightShare+0.01 {
+		t.Errorf("got interests share scaled by %f, want about %f", ratio, minimumWeightShare)
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	five, one := 5, 1
+	if got := Outcome(&five, 1); !approx(got, 1) {
+		t.Errorf("got %f for a 5 rating and full engagement", got)
+	}
+	if got := Outcome(&one, 0); got != 0 {
+		t.Errorf("got %f for a 1 rating and no engagement", got)
+	}
+	if got := Outcome(nil, 0.4); got != 0.4 {
+		t.Errorf("got %f without a rating, want the engagement", got)
+	}
+	if got := Engagement(EngagedMessageCount, 0); !approx(got, 0.5) {
+		t.Errorf("got %f for one-sided messages, want 0.5", got)
+	}
+}
@@ -0,0 +1,276 @@
+package compatibility
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+const (
+	GroupMinSize = 3
+	GroupMaxSize = 6
+)
+
+// Members joining a group must average at least MinimumGroupAffinity with
+// those already in it.
+const MinimumGroupAffinity = 0.1
+
+const maxGroupThemes = 5
+
+type Group struct {
+	Members  []model.User
+	Themes   []string
+	Affinity float64
+}
+
+func hobbies(profile *model.InternalProfile) []weightedText {
+	items := make([]weightedText, len(profile.Hobbies))
+	for i, hobby := range profile.Hobbies {
+		items[i] = weightedText{hobby, 1}
+	}
+
+	return items
+}
+
+func groupItems(profile *model.InternalProfile) []weightedText {
+	items := append(interests(profile), topics(profile)...)
+	return append(items, hobbies(profile)...)
+}
+
+// Affinity is how much two users have in common across their interests,
+// topics and hobbies.
+func Affinity(a, b *model.InternalProfile) float64 {
+	return overlap(groupItems(a), groupItems(b))
+}
+
+type groupPool struct {
+	users    []model.User
+	affinity [][]float64
+	allowed  [][]bool
+}
+
+func newGroupPool(users []model.User, allowed func(a, b string) bool) groupPool {
+	withProfiles := []model.User{}
+	for _, user := range users {
+		if user.Profile != nil {
+			withProfiles = append(withProfiles, user)
+		}
+	}
+
+	n := len(withProfiles)
+	pool := groupPool{withProfiles, make([][]float64, n), make([][]bool, n)}
+	for i := range withProfiles {
+		pool.affinity[i] = make([]float64, n)
+		pool.allowed[i] = make([]bool, n)
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			a, b := withProfiles[i], withProfiles[j]
+			ok := (allowed == nil || allowed(a.Id, b.Id)) &&
+				languages(a.Profile.Demographics.SpokenLanguages, b.Profile.Demographics.SpokenLanguages) > 0
+			pool.allowed[i][j], pool.allowed[j][i] = ok, ok
+
+			affinity := Affinity(a.Profile, b.Profile)
+			pool.affinity[i][j], pool.affinity[j][i] = affinity, affinity
+		}
+	}
+
+	return pool
+}
+
+// grow adds the unassigned user with the highest average affinity to the
+// group until it is full or nobody fits.
+func (pool groupPool) grow(members []int, assigned []bool) []int {
+	for len(members) < GroupMaxSize {
+		best, bestAffinity := -1, MinimumGroupAffinity
+		for candidate := range pool.users {
+			if assigned[candidate] {
+				continue
+			}
+
+			var total float64
+			fits := true
+			for _, member := range members {
+				if !pool.allowed[candidate][member] {
+					fits = false
+					break
+				}
+				total += pool.affinity[candidate][member]
+			}
+
+			if average := total / float64(len(members)); fits && average > bestAffinity {
+				best, bestAffinity = candidate, average
+			}
+		}
+
+		if best == -1 {
+			break
+		}
+		members = append(members, best)
+		assigned[best] = true
+	}
+
+	return members
+}
+
+func (pool groupPool) group(members []int) Group {
+	group := Group{Members: make([]model.User, len(members))}
+	var total float64
+	var pairs int
+	for i, member := range members {
+		group.Members[i] = pool.users[member]
+		for _, other := range members[i+1:] {
+			total += pool.affinity[member][other]
+			pairs++
+		}
+	}
+	if pairs > 0 {
+		group.Affinity = total / float64(pairs)
+	}
+	group.Themes = themes(group.Members)
+
+	return group
+}
+
+// FormGroup builds a group around seed from users, or returns false if too
+// few users have enough in common with seed and each other.
+func FormGroup(seed model.User, users []model.User, allowed func(a, b string) bool) (Group, bool) {
+	if seed.Profile == nil {
+		return Group{}, false
+	}
+
+	others := []model.User{seed}
+	for _, user := range users {
+		if user.Id != seed.Id {
+			others = append(others, user)
+		}
+	}
+
+	pool := newGroupPool(others, allowed)
+	assigned := make([]bool, len(pool.users))
+	assigned[0] = true
+
+	members := pool.grow([]int{0}, assigned)
+	if len(members) < GroupMinSize {
+		return Group{}, false
+	}
+
+	return pool.group(members), true
+}
+
+// Groups clusters users into disjoint groups, seeding each group with the
+// most similar pair of users still unassigned. Users who fit no group of at
+// least GroupMinSize are left out.
+func Groups(users []model.User, allowed func(a, b string) bool) []Group {
+	pool := newGroupPool(users, allowed)
+	n := len(pool.users)
+
+	type seedPair struct {
+		a, b     int
+		affinity float64
+	}
+	seeds := []seedPair{}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if pool.allowed[i][j] && pool.affinity[i][j] >= MinimumGroupAffinity {
+				seeds = append(seeds, seedPair{i, j, pool.affinity[i][j]})
+			}
+		}
+	}
+	sort.SliceStable(seeds, func(i, j int) bool {
+		return seeds[i].affinity > seeds[j].affinity
+	})
+
+	assigned := make([]bool, n)
+	groups := []Group{}
+	for _, seed := range seeds {
+		if assigned[seed.a] || assigned[seed.b] {
+			continue
+		}
+
+		assigned[seed.a], assigned[seed.b] = true, true
+		members := pool.grow([]int{seed.a, seed.b}, assigned)
+		if len(members) < GroupMinSize {
+			for _, member := range members {
+				assigned[member] = false
+			}
+			continue
+		}
+
+		groups = append(groups, pool.group(members))
+	}
+
+	return groups
+}
+
+// themes lists the interests, topics and hobbies that at least two members
+// share a word with, most widely shared first.
+func themes(members []model.User) []string {
+	type theme struct {
+		text    string
+		members map[int]bool
+	}
+
+	memberTokens := make([]map[string]bool, len(members))
+	for i, member := range members {
+		memberTokens[i] = map[string]bool{}
+		for _, item := range groupItems(member.Profile) {
+			for _, token := range tokens(item.text) {
+				memberTokens[i][token] = true
+			}
+		}
+	}
+
+	byText := map[string]*theme{}
+	ordered := []*theme{}
+	for i, member := range members {
+		for _, item := range groupItems(member.Profile) {
+			text := strings.ToLower(strings.TrimSpace(item.text))
+			if text == "" {
+				continue
+			}
+
+			t, ok := byText[text]
+			if !ok {
+				t = &theme{text, map[int]bool{}}
+				byText[text] = t
+				ordered = append(ordered, t)
+			}
+			t.members[i] = true
+
+			for j := range members {
+				if j == i {
+					continue
+				}
+				for _, token := range tokens(text) {
+					if memberTokens[j][token] {
+						t.members[j] = true
+						break
+					}
+				}
+			}
+		}
+	}
+
+	shared := []*theme{}
+	for _, t := range ordered {
+		if len(t.members) >= 2 {
+			shared = append(shared, t)
+		}
+	}
+	sort.SliceStable(shared, func(i, j int) bool {
+		return len(shared[i].members) > len(shared[j].members)
+	})
+
+	result := []string{}
+	for _, t := range shared {
+		if len(result) == maxGroupThemes {
+			break
+		}
+		result = append(result, t.text)
+	}
+
+	return result
+}
@@ -0,0 +1,95 @@
+package compatibility
+
+import (
+	"math"
+	"sort"
+
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+// Pair is a proposed match between two users.
+type Pair struct {
+	A      model.User
+	B      model.User
+	Scores model.CompatibilityScores
+}
+
+type edge struct {
+	a, b  int
+	score model.CompatibilityScores
+}
+
+// weightScale converts scores to the integer weights used for matching.
+const weightScale = 1e6
+
+// Pairings pairs up users so the total overall score is as high as possible.
+// Only pairs allowed by allowed that share a language and score at least
+// MinimumScore are considered.
+//
+// Each round solves a maximum weight matching over the pairs not yet chosen,
+// so after perUser rounds every user has at most perUser new partners.
+func Pairings(users []model.User, perUser int, allowed func(a, b string) bool) []Pair {
+	if perUser < 1 {
+		perUser = 1
+	}
+
+	candidates := []edge{}
+	for i := range users {
+		if users[i].Profile == nil {
+			continue
+		}
+		for j := i + 1; j < len(users); j++ {
+			if users[j].Profile == nil || (allowed != nil && !allowed(users[i].Id, users[j].Id)) {
+				continue
+			}
+
+			scores := Score(users[i].Profile, users[j].Profile)
+			if scores.Languages == 0 || scores.Overall < MinimumScore {
+				continue
+			}
+
+			candidates = append(candidates, edge{i, j, scores})
+		}
+	}
+
+	pairs := []Pair{}
+	for _, e := range choosePairs(len(users), candidates, perUser) {
+		pairs = append(pairs, Pair{users[e.a], users[e.b], e.score})
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Scores.Overall != pairs[j].Scores.Overall {
+			return pairs[i].Scores.Overall > pairs[j].Scores.Overall
+		}
+		return pairs[i].A.Id+pairs[i].B.Id < pairs[j].A.Id+pairs[j].B.Id
+	})
+
+	return pairs
+}
+
+func choosePairs(vertices int, candidates []edge, rounds int) []edge {
+	chosen := []edge{}
+	for round := 0; round < rounds && len(candidates) > 0; round++ {
+		weighted := make([]weightedEdge, len(candidates))
+		for k, e := range candidates {
+			weighted[k] = weightedEdge{e.a, e.b, 2 * int64(math.Round(e.score.Overall*weightScale))}
+		}
+
+		mate := maxWeightMatching(vertices, weighted)
+
+		remaining := candidates[:0:0]
+		for _, e := range candidates {
+			if mate[e.a] == e.b {
+				chosen = append(chosen, e)
+			} else {
+				remaining = append(remaining, e)
+			}
+		}
+		if len(remaining) == len(candidates) {
+			break
+		}
+		candidates = remaining
+	}
+
+	return chosen
+}
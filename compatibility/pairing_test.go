@@ -0,0 +1,256 @@
+package compatibility
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+func edgesOf(triples [][3]int) []weightedEdge {
+	edges := make([]weightedEdge, len(triples))
+	for k, t := range triples {
+		edges[k] = weightedEdge{t[0], t[1], int64(t[2])}
+	}
+	return edges
+}
+
+// matchingWeight checks that mate is a matching using only the given edges
+// and returns its total weight.
+func matchingWeight(t *testing.T, vertices int, edges []weightedEdge, mate []int) int64 {
+	t.Helper()
+
+	if len(mate) != vertices {
+		t.Fatalf("got %d mates for %d vertices", len(mate), vertices)
+	}
+
+	weights := map[[2]int]int64{}
+	for _, e := range edges {
+		weights[[2]int{e.i, e.j}] = e.weight
+		weights[[2]int{e.j, e.i}] = e.weight
+	}
+
+	var total int64
+	for v, w := range mate {
+		if w == -1 {
+			continue
+		}
+		if w < 0 || w >= vertices || mate[w] != v {
+			t.Fatalf("mate is not symmetric at %d: %v", v, mate)
+		}
+		weight, ok := weights[[2]int{v, w}]
+		if !ok {
+			t.Fatalf("matched %d and %d without an edge", v, w)
+		}
+		if v < w {
+			total += weight
+		}
+	}
+
+	return total
+}
+
+// bruteForceWeight tries every matching.
+func bruteForceWeight(vertices int, edges []weightedEdge) int64 {
+	adjacent := make([][]weightedEdge, vertices)
+	for _, e := range edges {
+		adjacent[e.i] = append(adjacent[e.i], e)
+		adjacent[e.j] = append(adjacent[e.j], weightedEdge{e.j, e.i, e.weight})
+	}
+
+	matched := make([]bool, vertices)
+	var best func(v int) int64
+	best = func(v int) int64 {
+		for v < vertices && matched[v] {
+			v++
+		}
+		if v == vertices {
+			return 0
+		}
+
+		matched[v] = true
+		result := best(v + 1)
+		for _, e := range adjacent[v] {
+			if matched[e.j] {
+				continue
+			}
+			matched[e.j] = true
+			result = max(result, e.weight+best(v+1))
+			matched[e.j] = false
+		}
+		matched[v] = false
+
+		return result
+	}
+
+	return best(0)
+}
+
+// Cases from van Rantwijk's reference tests, covering odd cycles, blossoms
+// relabelled as T and nested blossoms that must be expanded.
+var blossomCases = []struct {
+	name  string
+	edges [][3]int
+	mate  []int
+}{
+	{"empty", nil, []int{-1}},
+	{"single edge", [][3]int{{0, 1, 1}}, []int{1, 0}},
+	{"path prefers heavy middle", [][3]int{{1, 2, 10}, {2, 3, 11}}, []int{-1, -1, 3, 2}},
+	{"path prefers both ends", [][3]int{{1, 2, 5}, {2, 3, 11}, {3, 4, 5}}, []int{-1, -1, 3, 2, -1}},
+	{"triangle", [][3]int{{0, 1, 3}, {1, 2, 4}, {0, 2, 5}}, []int{2, -1, 0}},
+	{"s-blossom", [][3]int{{1, 2, 8}, {1, 3, 9}, {2, 3, 10}, {3, 4, 7}}, []int{-1, 2, 1, 4, 3}},
+	{"s-blossom augmented", [][3]int{{1, 2, 8}, {1, 3, 9}, {2, 3, 10}, {3, 4, 7}, {1, 6, 5}, {4, 5, 6}}, []int{-1, 6, 3, 2, 5, 4, 1}},
+	{"t-blossom", [][3]int{{1, 2, 9}, {1, 3, 8}, {2, 3, 10}, {1, 4, 5}, {4, 5, 4}, {1, 6, 3}}, []int{-1, 6, 3, 2, 5, 4, 1}},
+	{"nested s-blossom", [][3]int{{1, 2, 9}, {1, 3, 9}, {2, 3, 10}, {2, 4, 8}, {3, 5, 8}, {4, 5, 10}, {5, 6, 6}}, []int{-1, 3, 4, 1, 2, 6, 5}},
+	{"nested s-blossom relabelled", [][3]int{{1, 2, 10}, {1, 7, 10}, {2, 3, 12}, {3, 4, 20}, {3, 5, 20}, {4, 5, 25}, {5, 6, 10}, {6, 7, 10}, {7, 8, 8}}, []int{-1, 2, 1, 4, 3, 6, 5, 8, 7}},
+	{"nested s-blossom expanded", [][3]int{{1, 2, 8}, {1, 3, 8}, {2, 3, 10}, {2, 4, 12}, {3, 5, 12}, {4, 5, 14}, {4, 6, 12}, {5, 7, 12}, {6, 7, 14}, {7, 8, 12}}, []int{-1, 2, 1, 5, 6, 3, 4, 8, 7}},
+	{"s-blossom relabelled as t and expanded", [][3]int{{1, 2, 23}, {1, 5, 22}, {1, 6, 15}, {2, 3, 25}, {3, 4, 22}, {4, 5, 25}, {4, 8, 14}, {5, 7, 13}}, []int{-1, 6, 3, 2, 8, 7, 1, 5, 4}},
+	{"nested s-blossom relabelled as t and expanded", [][3]int{{1, 2, 19}, {1, 3, 20}, {1, 8, 8}, {2, 3, 25}, {2, 4, 18}, {3, 5, 18}, {4, 5, 13}, {4, 7, 7}, {5, 6, 7}}, []int{-1, 8, 3, 2, 7, 6, 5, 4, 1}},
+	{"t-blossom expanded with nasty augment", [][3]int{{1, 2, 45}, {1, 5, 45}, {2, 3, 50}, {3, 4, 45}, {4, 5, 50}, {1, 6, 30}, {3, 9, 35}, {4, 8, 35}, {5, 7, 26}, {9, 10, 5}}, []int{-1, 6, 3, 2, 8, 7, 1, 5, 4, 10, 9}},
+	{"t-blossom expanded with least slack edge", [][3]int{{1, 2, 45}, {1, 5, 45}, {2, 3, 50}, {3, 4, 45}, {4, 5, 50}, {1, 6, 30}, {3, 9, 35}, {4, 8, 28}, {5, 7, 26}, {9, 10, 5}}, []int{-1, 6, 3, 2, 8, 7, 1, 5, 4, 10, 9}},
+	{"nested t-blossom expanded", [][3]int{{1, 2, 45}, {1, 7, 45}, {2, 3, 50}, {3, 4, 45}, {4, 5, 95}, {4, 6, 94}, {5, 6, 94}, {6, 7, 50}, {1, 8, 30}, {3, 11, 35}, {5, 9, 36}, {7, 10, 26}, {11, 12, 5}}, []int{-1, 8, 3, 2, 6, 9, 4, 10, 1, 5, 7, 12, 11}},
+	{"nested s-blossom relabelled and expanded", [][3]int{{1, 2, 40}, {1, 3, 40}, {2, 3, 60}, {2, 4, 55}, {3, 5, 55}, {4, 5, 50}, {1, 8, 15}, {5, 7, 30}, {7, 6, 10}, {8, 10, 10}, {4, 9, 30}}, []int{-1, 2, 1, 5, 9, 3, 7, 6, 10, 4, 8}},
+}
+
+func TestMaxWeightMatching(t *testing.T) {
+	for _, tc := range blossomCases {
+		t.Run(tc.name, func(t *testing.T) {
+			edges := edgesOf(tc.edges)
+			mate := maxWeightMatching(len(tc.mate), edges)
+
+			if !reflect.DeepEqual(mate, tc.mate) {
+				t.Errorf("got %v, want %v", mate, tc.mate)
+			}
+			if got, want := matchingWeight(t, len(tc.mate), edges, mate), bruteForceWeight(len(tc.mate), edges); got != want {
+				t.Errorf("got weight %d, brute force found %d", got, want)
+			}
+		})
+	}
+}
+
+func TestMaxWeightMatchingRandomGraphs(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	for run := 0; run < 2000; run++ {
+		vertices := 1 + random.Intn(10)
+		density := random.Float64()
+		maxWeight := 1 + random.Intn(100)
+
+		var edges []weightedEdge
+		for i := 0; i < vertices; i++ {
+			for j := i + 1; j < vertices; j++ {
+				if random.Float64() < density {
+					edges = append(edges, weightedEdge{i, j, int64(1 + random.Intn(maxWeight))})
+				}
+			}
+		}
+
+		t.Run(fmt.Sprint(run), func(t *testing.T) {
+			mate := maxWeightMatching(vertices, edges)
+			if got, want := matchingWeight(t, vertices, edges, mate), bruteForceWeight(vertices, edges); got != want {
+				t.Fatalf("got weight %d, brute force found %d for %v", got, want, edges)
+			}
+		})
+	}
+}
+
+func pairingUser(id string, interests ...string) model.User {
+	profile := &model.InternalProfile{
+		Personality: model.Personality{Extroversion: 2.5, Agreeableness: 2.5, Conscientiousness: 2.5, Neuroticism: 2.5, Openness: 2.5},
+		Demographics: model.Demographics{
+			SpokenLanguages: []string{"English"},
+		},
+	}
+	for _, interest := range interests {
+		profile.Interests = append(profile.Interests, model.Interest{Interest: interest, Level: 1})
+	}
+
+	return model.User{Id: id, Profile: profile}
+}
+
+func TestPairings(t *testing.T) {
+	users := []model.User{
+		pairingUser("a", "chess", "hiking"),
+		pairingUser("b", "chess", "hiking"),
+		pairingUser("c", "chess", "baking"),
+		pairingUser("d", "hiking", "baking"),
+		pairingUser("e", "painting"),
+		pairingUser("f", "painting", "chess"),
+		{Id: "g"},
+	}
+
+	blocked := map[[2]string]bool{{"a", "b"}: true, {"e", "f"}: true}
+	matched := map[[2]string]bool{{"c", "d"}: true}
+	allowed := func(a, b string) bool {
+		for _, pair := range [][2]string{{a, b}, {b, a}} {
+			if blocked[pair] || matched[pair] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for perUser := 1; perUser <= 3; perUser++ {
+		t.Run(fmt.Sprintf("%d per user", perUser), func(t *testing.T) {
+			pairs := Pairings(users, perUser, allowed)
+			if len(pairs) == 0 {
+				t.Fatal("expected some pairs")
+			}
+
+			partners := map[string]int{}
+			seen := map[[2]string]bool{}
+			for _, pair := range pairs {
+				if !allowed(pair.A.Id, pair.B.Id) {
+					t.Errorf("paired %s and %s, who are blocked or already matched", pair.A.Id, pair.B.Id)
+				}
+				if pair.A.Id == "g" || pair.B.Id == "g" {
+					t.Errorf("paired %s and %s, but g has no profile", pair.A.Id, pair.B.Id)
+				}
+				key := [2]string{min(pair.A.Id, pair.B.Id), max(pair.A.Id, pair.B.Id)}
+				if seen[key] {
+					t.Errorf("paired %s and %s twice", pair.A.Id, pair.B.Id)
+				}
+				seen[key] = true
+				partners[pair.A.Id]++
+				partners[pair.B.Id]++
+			}
+			for id, count := range partners {
+				if count > perUser {
+					t.Errorf("%s got %d partners, want at most %d", id, count, perUser)
+				}
+			}
+		})
+	}
+}
+
+func TestPairingsMaximizesTotalScore(t *testing.T) {
+	// a-b is the best single pair, but pairing a-c and b-d scores more in total.
+	users := []model.User{
+		pairingUser("a", "chess", "hiking", "baking"),
+		pairingUser("b", "chess", "hiking", "painting"),
+		pairingUser("c", "baking"),
+		pairingUser("d", "painting"),
+	}
+
+	pairs := Pairings(users, 1, nil)
+
+	var total float64
+	for _, pair := range pairs {
+		total += pair.Scores.Overall
+	}
+
+	var best float64
+	for _, split := range [][2][2]int{{{0, 1}, {2, 3}}, {{0, 2}, {1, 3}}, {{0, 3}, {1, 2}}} {
+		var sum float64
+		for _, p := range split {
+			sum += Score(users[p[0]].Profile, users[p[1]].Profile).Overall
+		}
+		best = max(best, sum)
+	}
+
+	if total < best-1e-6 {
+		t.Fatalf("got total score %f from %d pairs, best is %f", total, len(pairs), best)
+	}
+}
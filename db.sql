@@ -6,6 +6,8 @@ CREATE TABLE IF NOT EXISTS `users` (
     `avatar` TEXT,
     `updated_at` DATETIME NOT NULL,
     `profile` JSONB,
+    `prompt_versions` JSONB,
+    `profile_correlation_id` VARCHAR(36),
     `generated_at` DATETIME
 );
 
@@ -14,22 +16,145 @@ CREATE TABLE IF NOT EXISTS `matches` (
     `user_id` VARCHAR(36),
     `other_id` VARCHAR(36),
     `reason` TEXT NOT NULL,
+    `prompt_versions` JSONB,
+    `correlation_id` VARCHAR(36),
+    `compatibility` JSONB,
+    `explanation` JSONB,
+    `status` TEXT NOT NULL DEFAULT 'pending',
     `created_at` DATETIME NOT NULL,
+    `expires_at` DATETIME,
     CONSTRAINT `fk_user_id` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
     CONSTRAINT `fk_other_id` FOREIGN KEY (`other_id`) REFERENCES `users`(`id`)
     CONSTRAINT `unique_match` UNIQUE (`user_id`, `other_id`)
 );
 
+CREATE INDEX IF NOT EXISTS idx_matches_status_expires_at ON matches (status, expires_at);
+
+CREATE TABLE IF NOT EXISTS `match_feedback` (
+    `match_id` VARCHAR(36) PRIMARY KEY,
+    `rating` INTEGER NOT NULL CHECK (`rating` BETWEEN 1 AND 5),
+    `tags` JSONB NOT NULL,
+    `created_at` DATETIME NOT NULL,
+    `updated_at` DATETIME NOT NULL,
+    CONSTRAINT `fk_match_id` FOREIGN KEY (`match_id`) REFERENCES `matches`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `match_runs` (
+    `id` VARCHAR(36) PRIMARY KEY,
+    `match_id` VARCHAR(36),
+    `user_id` VARCHAR(36) NOT NULL,
+    `correlation_id` VARCHAR(36) NOT NULL,
+    `pool_size` INTEGER NOT NULL,
+    `weights` JSONB NOT NULL,
+    `candidates` JSONB NOT NULL,
+    `chosen_id` VARCHAR(36) NOT NULL,
+    `reasoning` TEXT NOT NULL,
+    `fallback` BOOLEAN NOT NULL,
+    `models` JSONB NOT NULL,
+    `timings` JSONB NOT NULL,
+    `prompt_versions` JSONB NOT NULL,
+    `outcome` TEXT NOT NULL,
+    `error` TEXT,
+    `created_at` DATETIME NOT NULL,
+    CONSTRAINT `fk_user_id` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_runs_correlation_id ON match_runs (correlation_id);
+
+CREATE TABLE IF NOT EXISTS `groups` (
+    `id` VARCHAR(36) PRIMARY KEY,
+    `name` TEXT NOT NULL,
+    `intro` TEXT NOT NULL,
+    `themes` JSONB NOT NULL,
+    `prompt_versions` JSONB,
+    `correlation_id` VARCHAR(36),
+    `created_at` DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS `group_members` (
+    `group_id` VARCHAR(36) NOT NULL,
+    `user_id` VARCHAR(36) NOT NULL,
+    `explanation` TEXT NOT NULL,
+    PRIMARY KEY (`group_id`, `user_id`),
+    CONSTRAINT `fk_group_id` FOREIGN KEY (`group_id`) REFERENCES `groups`(`id`),
+    CONSTRAINT `fk_user_id` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+CREATE INDEX IF NOT EXISTS idx_group_members_user_id ON group_members (user_id);
+
 CREATE TABLE IF NOT EXISTS `messages` (
     `id` VARCHAR(36) PRIMARY KEY,
     `sender_id` VARCHAR(36),
     `receiver_id` VARCHAR(36),
     `message` TEXT NOT NULL,
     `created_at` DATETIME NOT NULL,
+    `archived_at` DATETIME,
     CONSTRAINT `fk_sender_id` FOREIGN KEY (`sender_id`) REFERENCES `users`(`id`),
     CONSTRAINT `fk_receiver_id` FOREIGN KEY (`receiver_id`) REFERENCES `users`(`id`)
 );
-CREATE INDEX idx_sender_id ON messages (sender_id);
-CREATE INDEX idx_receiver_id ON messages (receiver_id);
-CREATE INDEX idx_created_at ON messages (created_at);
-CREATE INDEX idx_sender_receiver_created_at ON messages (sender_id, receiver_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_sender_id ON messages (sender_id);
+CREATE INDEX IF NOT EXISTS idx_receiver_id ON messages (receiver_id);
+CREATE INDEX IF NOT EXISTS idx_created_at ON messages (created_at);
+CREATE INDEX IF NOT EXISTS idx_sender_receiver_created_at ON messages (sender_id, receiver_id, created_at);
+
+CREATE TABLE IF NOT EXISTS `blocks` (
+    `blocker_id` VARCHAR(36) NOT NULL,
+    `blocked_id` VARCHAR(36) NOT NULL,
+    `created_at` DATETIME NOT NULL,
+    PRIMARY KEY (`blocker_id`, `blocked_id`),
+    CONSTRAINT `fk_blocker_id` FOREIGN KEY (`blocker_id`) REFERENCES `users`(`id`),
+    CONSTRAINT `fk_blocked_id` FOREIGN KEY (`blocked_id`) REFERENCES `users`(`id`)
+);
+CREATE INDEX IF NOT EXISTS idx_blocks_blocked_id ON blocks (blocked_id);
+
+CREATE TABLE IF NOT EXISTS `llm_cache` (
+    `key` VARCHAR(64) PRIMARY KEY,
+    `response` TEXT NOT NULL,
+    `created_at` DATETIME NOT NULL,
+    `expires_at` DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_llm_cache_created_at ON llm_cache (created_at);
+
+CREATE TABLE IF NOT EXISTS `llm_usage` (
+    `id` VARCHAR(36) PRIMARY KEY,
+    `user_id` VARCHAR(36) NOT NULL,
+    `feature` TEXT NOT NULL,
+    `model` TEXT NOT NULL,
+    `input_tokens` INTEGER NOT NULL,
+    `output_tokens` INTEGER NOT NULL,
+    `cost` REAL NOT NULL,
+    `latency_ms` INTEGER NOT NULL,
+    `created_at` DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_llm_usage_user_created_at ON llm_usage (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_llm_usage_created_at ON llm_usage (created_at);
+
+CREATE TABLE IF NOT EXISTS `llm_calls` (
+    `id` VARCHAR(36) PRIMARY KEY,
+    `correlation_id` VARCHAR(36) NOT NULL,
+    `user_id` VARCHAR(36) NOT NULL,
+    `caller` TEXT NOT NULL,
+    `model` TEXT NOT NULL,
+    `system` TEXT NOT NULL,
+    `prompt` TEXT NOT NULL,
+    `response` TEXT,
+    `outcome` TEXT NOT NULL,
+    `parse_strategy` TEXT,
+    `error` TEXT,
+    `step` INTEGER NOT NULL,
+    `retry` INTEGER NOT NULL,
+    `latency_ms` INTEGER NOT NULL,
+    `created_at` DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_llm_calls_correlation_id ON llm_calls (correlation_id);
+CREATE INDEX IF NOT EXISTS idx_llm_calls_user_created_at ON llm_calls (user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS `embeddings` (
+    `kind` TEXT NOT NULL,
+    `owner_id` VARCHAR(36) NOT NULL,
+    `model` TEXT NOT NULL,
+    `text_hash` VARCHAR(64) NOT NULL,
+    `vector` BLOB NOT NULL,
+    `updated_at` DATETIME NOT NULL,
+    PRIMARY KEY (`kind`, `owner_id`)
+);
+CREATE INDEX IF NOT EXISTS idx_embeddings_model ON embeddings (model);
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"database/sql"
+)
+
+type BlockStore struct {
+	db *sql.DB
+}
+
+func NewBlockStore(db *sql.DB) BlockStore {
+	return BlockStore{db}
+}
+
+// CreateBlock records the block and, in the same transaction, closes any
+// match between the two users and archives their conversation.
+func (store *BlockStore) CreateBlock(blockerId, blockedId string) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT INTO blocks (blocker_id, blocked_id, created_at)
+		 VALUES (?, ?, datetime('now'))
+		 ON CONFLICT (blocker_id, blocked_id) DO NOTHING`,
+		blockerId, blockedId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE matches
+		 SET status = 'closed'
+		 WHERE (user_id, other_id) IN ((?, ?), (?, ?)) AND status IN ('pending', 'accepted', 'active')`,
+		blockerId, blockedId, blockedId, blockerId)
+	if err != nil {
+		return err
+	}
+
+	if err := archiveConversation(tx, blockerId, blockedId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (store *BlockStore) IsBlocked(blockerId, blockedId string) (bool, error) {
+	var blocked bool
+	row := store.db.QueryRow("SELECT EXISTS (SELECT 1 FROM blocks WHERE blocker_id = ? AND blocked_id = ?)", blockerId, blockedId)
+	if err := row.Scan(&blocked); err != nil {
+		return false, err
+	}
+
+	return blocked, nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type CacheStore struct {
+	db *sql.DB
+}
+
+func NewCacheStore(db *sql.DB) CacheStore {
+	return CacheStore{db}
+}
+
+func (store *CacheStore) GetCachedResponse(key string) (*string, error) {
+	row := store.db.QueryRow(
+		`SELECT response
+		 FROM llm_cache
+		 WHERE key = ? AND expires_at > datetime('now')`,
+		key)
+
+	var response string
+	if err := row.Scan(&response); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (store *CacheStore) DeleteCachedResponse(key string) error {
+	_, err := store.db.Exec("DELETE FROM llm_cache WHERE key = ?", key)
+	return err
+}
+
+func (store *CacheStore) PutCachedResponse(key, response string, ttl time.Duration, maxEntries int) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT OR REPLACE INTO llm_cache (key, response, created_at, expires_at)
+		 VALUES (?, ?, datetime('now'), datetime('now', ?))`,
+		key, response, fmt.Sprintf("+%d seconds", int64(ttl.Seconds())))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`DELETE FROM llm_cache
+		 WHERE expires_at <= datetime('now')
+		 OR key IN (
+			 SELECT key
+			 FROM llm_cache
+			 ORDER BY created_at DESC
+			 LIMIT -1 OFFSET ?
+		 )`,
+		maxEntries)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type CallStore struct {
+	db *sql.DB
+}
+
+func NewCallStore(db *sql.DB) CallStore {
+	return CallStore{db}
+}
+
+type Call struct {
+	Id            string
+	CorrelationId string
+	UserId        string
+	Caller        string
+	Model         string
+	System        string
+	Prompt        string
+	Response      *string
+	Outcome       string
+	Strategy      *string
+	Error         *string
+	Step          int
+	Retry         int
+	LatencyMs     int64
+	CreatedAt     string
+}
+
+type CreateCall struct {
+	CorrelationId string
+	UserId        string
+	Caller        string
+	Model         string
+	System        string
+	Prompt        string
+	Response      *string
+	Outcome       string
+	Strategy      *string
+	Error         *string
+	Step          int
+	Retry         int
+	LatencyMs     int64
+}
+
+func (store *CallStore) CreateCall(call CreateCall) error {
+	_, err := store.db.Exec(
+		`INSERT INTO llm_calls (id, correlation_id, user_id, caller, model, system, prompt, response, outcome, parse_strategy, error, step, retry, latency_ms, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))`,
+		uuid.New().String(), call.CorrelationId, call.UserId, call.Caller, call.Model, call.System, call.Prompt,
+		call.Response, call.Outcome, call.Strategy, call.Error, call.Step, call.Retry, call.LatencyMs)
+
+	return err
+}
+
+const callColumns = `id, correlation_id, user_id, caller, model, system, prompt, response, outcome, parse_strategy, error, step, retry, latency_ms, created_at`
+
+func (store *CallStore) GetUserCalls(userId string, limit int) ([]Call, error) {
+	return store.queryCalls(
+		`SELECT `+callColumns+`
+		 FROM llm_calls
+		 WHERE user_id = ?
+		 ORDER BY created_at DESC
+		 LIMIT ?`,
+		userId, limit)
+}
+
+func (store *CallStore) GetCorrelatedCalls(correlationId string) ([]Call, error) {
+	return store.queryCalls(
+		`SELECT `+callColumns+`
+		 FROM llm_calls
+		 WHERE correlation_id = ?
+		 ORDER BY created_at`,
+		correlationId)
+}
+
+func (store *CallStore) GetMatchCalls(matchId string) ([]Call, error) {
+	return store.queryCalls(
+		`SELECT `+callColumns+`
+		 FROM llm_calls
+		 WHERE correlation_id = (SELECT correlation_id FROM matches WHERE id = ?)
+		 ORDER BY created_at`,
+		matchId)
+}
+
+func (store *CallStore) queryCalls(query string, args ...any) ([]Call, error) {
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	calls := []Call{}
+	for rows.Next() {
+		call := Call{}
+		if err := rows.Scan(&call.Id, &call.CorrelationId, &call.UserId, &call.Caller, &call.Model, &call.System,
+			&call.Prompt, &call.Response, &call.Outcome, &call.Strategy, &call.Error, &call.Step, &call.Retry, &call.LatencyMs, &call.CreatedAt); err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+
+	return calls, nil
+}
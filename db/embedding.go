@@ -0,0 +1,67 @@
+package db
+
+import (
+	"database/sql"
+)
+
+type EmbeddingStore struct {
+	db *sql.DB
+}
+
+func NewEmbeddingStore(db *sql.DB) EmbeddingStore {
+	return EmbeddingStore{db}
+}
+
+type Embedding struct {
+	Kind     string
+	OwnerId  string
+	Model    string
+	TextHash string
+	Vector   []byte
+}
+
+func (store *EmbeddingStore) GetEmbeddings(model string) ([]Embedding, error) {
+	rows, err := store.db.Query(
+		`SELECT kind, owner_id, model, text_hash, vector
+		 FROM embeddings
+		 WHERE model = ?`,
+		model)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	embeddings := []Embedding{}
+	for rows.Next() {
+		embedding := Embedding{}
+		if err := rows.Scan(&embedding.Kind, &embedding.OwnerId, &embedding.Model, &embedding.TextHash, &embedding.Vector); err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, embedding)
+	}
+
+	return embeddings, nil
+}
+
+func (store *EmbeddingStore) PutEmbedding(embedding Embedding) error {
+	_, err := store.db.Exec(
+		`INSERT INTO embeddings (kind, owner_id, model, text_hash, vector, updated_at)
+		 VALUES (?, ?, ?, ?, ?, datetime('now'))
+		 ON CONFLICT (kind, owner_id) DO UPDATE
+		 SET model = excluded.model, text_hash = excluded.text_hash, vector = excluded.vector, updated_at = excluded.updated_at`,
+		embedding.Kind, embedding.OwnerId, embedding.Model, embedding.TextHash, embedding.Vector)
+
+	return err
+}
+
+func (store *EmbeddingStore) DeleteEmbedding(kind, ownerId string) error {
+	_, err := store.db.Exec("DELETE FROM embeddings WHERE kind = ? AND owner_id = ?", kind, ownerId)
+
+	return err
+}
+
+func (store *EmbeddingStore) DeleteEmbeddings(ownerId string) error {
+	_, err := store.db.Exec("DELETE FROM embeddings WHERE owner_id = ?", ownerId)
+
+	return err
+}
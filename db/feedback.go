@@ -0,0 +1,105 @@
+package db
+
+import (
+	"database/sql"
+)
+
+type FeedbackStore struct {
+	db *sql.DB
+}
+
+func NewFeedbackStore(db *sql.DB) FeedbackStore {
+	return FeedbackStore{db}
+}
+
+func (store *FeedbackStore) PutFeedback(matchId string, rating int, tags string) error {
+	_, err := store.db.Exec(
+		`INSERT INTO match_feedback (match_id, rating, tags, created_at, updated_at)
+		 VALUES (?, ?, ?, datetime('now'), datetime('now'))
+		 ON CONFLICT (match_id) DO UPDATE
+		 SET rating = excluded.rating, tags = excluded.tags, updated_at = excluded.updated_at`,
+		matchId, rating, tags)
+
+	return err
+}
+
+// MatchSignals is what is known about how one direction of a match went.
+type MatchSignals struct {
+	MatchId       string
+	UserId        string
+	Status        string
+	Compatibility string
+	Rating        *int
+	Sent          int
+	Received      int
+}
+
+// GetMatchSignals returns the signals of every match that has been answered
+// or rated, limited to matches shown to userId unless it is empty.
+func (store *FeedbackStore) GetMatchSignals(userId string) ([]MatchSignals, error) {
+	rows, err := store.db.Query(
+		`SELECT matches.id, matches.user_id, matches.status, matches.compatibility, match_feedback.rating,
+		        (SELECT COUNT(*) FROM messages WHERE sender_id = matches.user_id AND receiver_id = matches.other_id),
+		        (SELECT COUNT(*) FROM messages WHERE sender_id = matches.other_id AND receiver_id = matches.user_id)
+		 FROM matches
+		 LEFT JOIN match_feedback ON match_feedback.match_id = matches.id
+		 WHERE matches.compatibility IS NOT NULL
+		 AND (matches.status IN ('active', 'closed', 'declined') OR match_feedback.rating IS NOT NULL)
+		 AND (? = '' OR matches.user_id = ?)`,
+		userId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	signals := []MatchSignals{}
+	for rows.Next() {
+		signal := MatchSignals{}
+		if err := rows.Scan(&signal.MatchId, &signal.UserId, &signal.Status, &signal.Compatibility, &signal.Rating, &signal.Sent, &signal.Received); err != nil {
+			return nil, err
+		}
+		signals = append(signals, signal)
+	}
+
+	return signals, nil
+}
+
+type Count struct {
+	Key   string
+	Count int
+}
+
+func (store *FeedbackStore) GetRatingCounts() ([]Count, error) {
+	return store.counts(
+		`SELECT CAST(rating AS TEXT), COUNT(*)
+		 FROM match_feedback
+		 GROUP BY rating
+		 ORDER BY rating`)
+}
+
+func (store *FeedbackStore) GetTagCounts() ([]Count, error) {
+	return store.counts(
+		`SELECT json_each.value, COUNT(*)
+		 FROM match_feedback, json_each(match_feedback.tags)
+		 GROUP BY json_each.value
+		 ORDER BY COUNT(*) DESC`)
+}
+
+func (store *FeedbackStore) counts(query string) ([]Count, error) {
+	rows, err := store.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []Count{}
+	for rows.Next() {
+		count := Count{}
+		if err := rows.Scan(&count.Key, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type GroupStore struct {
+	db *sql.DB
+}
+
+func NewGroupStore(db *sql.DB) GroupStore {
+	return GroupStore{db}
+}
+
+type Group struct {
+	Id             string
+	Name           string
+	Intro          string
+	Themes         string
+	PromptVersions *string
+	CreatedAt      string
+	Members        []GroupMember
+}
+
+type GroupMember struct {
+	UserId      string
+	Name        string
+	Explanation string
+}
+
+type CreateGroup struct {
+	Name           string
+	Intro          string
+	Themes         string
+	PromptVersions string
+	CorrelationId  string
+	Members        []CreateGroupMember
+}
+
+type CreateGroupMember struct {
+	UserId      string
+	Explanation string
+}
+
+func (store *GroupStore) CreateGroup(group CreateGroup) (*string, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	id := uuid.New().String()
+	_, err = tx.Exec(
+		`INSERT INTO groups (id, name, intro, themes, prompt_versions, correlation_id, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, datetime('now'))`,
+		id, group.Name, group.Intro, group.Themes, group.PromptVersions, group.CorrelationId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range group.Members {
+		_, err = tx.Exec(
+			`INSERT INTO group_members (group_id, user_id, explanation)
+			 VALUES (?, ?, ?)`,
+			id, member.UserId, member.Explanation)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &id, tx.Commit()
+}
+
+func (store *GroupStore) GetGroup(id string) (Group, error) {
+	group := Group{}
+	row := store.db.QueryRow(
+		`SELECT id, name, intro, themes, prompt_versions, created_at
+		 FROM groups
+		 WHERE id = ?`,
+		id)
+	if err := row.Scan(&group.Id, &group.Name, &group.Intro, &group.Themes, &group.PromptVersions, &group.CreatedAt); err != nil {
+		return Group{}, err
+	}
+
+	rows, err := store.db.Query(
+		`SELECT group_members.user_id, users.name, group_members.explanation
+		 FROM group_members
+		 JOIN users ON users.id = group_members.user_id
+		 WHERE group_members.group_id = ?
+		 ORDER BY users.name`,
+		id)
+	if err != nil {
+		return Group{}, err
+	}
+	defer rows.Close()
+
+	group.Members = []GroupMember{}
+	for rows.Next() {
+		member := GroupMember{}
+		if err := rows.Scan(&member.UserId, &member.Name, &member.Explanation); err != nil {
+			return Group{}, err
+		}
+		group.Members = append(group.Members, member)
+	}
+
+	return group, nil
+}
+
+func (store *GroupStore) GetUserGroupIds(userId string) ([]string, error) {
+	rows, err := store.db.Query(
+		`SELECT group_members.group_id
+		 FROM group_members
+		 JOIN groups ON groups.id = group_members.group_id
+		 WHERE group_members.user_id = ?
+		 ORDER BY groups.created_at DESC`,
+		userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// GetUngroupablePairs returns the pairs of users that should not be put in a
+// new group together, because they already share one or one blocked the
+// other.
+func (store *GroupStore) GetUngroupablePairs() ([]UserPair, error) {
+	rows, err := store.db.Query(
+		`SELECT a.user_id, b.user_id
+		 FROM group_members a
+		 JOIN group_members b ON a.group_id = b.group_id AND a.user_id != b.user_id
+		 UNION
+		 SELECT blocker_id, blocked_id FROM blocks`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pairs := []UserPair{}
+	for rows.Next() {
+		pair := UserPair{}
+		if err := rows.Scan(&pair.UserId, &pair.OtherId); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
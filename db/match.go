@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 
 	"github.com/google/uuid"
 )
@@ -15,16 +16,21 @@ func NewMatchStore(db *sql.DB) MatchStore {
 }
 
 type Match struct {
-	Id        string
-	UserId    string
-	OtherId   string
-	Reason    string
-	CreatedAt string
+	Id             string
+	UserId         string
+	OtherId        string
+	Reason         string
+	PromptVersions *string
+	Compatibility  *string
+	Explanation    *string
+	Status         string
+	CreatedAt      string
+	ExpiresAt      *string
 }
 
 func (store *MatchStore) GetUserMatches(id string) ([]Match, error) {
 	rows, err := store.db.Query(
-		`SELECT id, user_id, other_id, reason, created_at
+		`SELECT id, user_id, other_id, reason, prompt_versions, compatibility, explanation, status, created_at, expires_at
 		 FROM matches
 		 WHERE user_id = ?`,
 		id)
@@ -37,7 +43,7 @@ func (store *MatchStore) GetUserMatches(id string) ([]Match, error) {
 	matches := []Match{}
 	for rows.Next() {
 		match := Match{}
-		if err := rows.Scan(&match.Id, &match.UserId, &match.OtherId, &match.Reason, &match.CreatedAt); err != nil {
+		if err := rows.Scan(&match.Id, &match.UserId, &match.OtherId, &match.Reason, &match.PromptVersions, &match.Compatibility, &match.Explanation, &match.Status, &match.CreatedAt, &match.ExpiresAt); err != nil {
 			return nil, err
 		}
 		matches = append(matches, match)
@@ -46,10 +52,20 @@ func (store *MatchStore) GetUserMatches(id string) ([]Match, error) {
 	return matches, nil
 }
 
+var (
+	ErrMatchExists   = errors.New("users are already matched")
+	ErrMatchConflict = errors.New("match was changed concurrently")
+)
+
 type CreateMatch struct {
-	UserId  string
-	OtherId string
-	Reason  string
+	UserId         string
+	OtherId        string
+	Reason         string
+	PromptVersions string
+	CorrelationId  string
+	Compatibility  string
+	Explanation    string
+	ExpiresAt      string
 }
 
 func (store *MatchStore) CreateMatch(a, b CreateMatch) (*string, error) {
@@ -60,22 +76,33 @@ func (store *MatchStore) CreateMatch(a, b CreateMatch) (*string, error) {
 	defer tx.Rollback()
 
 	stmt, err := tx.Prepare(
-		`INSERT INTO matches (id, user_id, other_id, reason, created_at)
-		 VALUES (?, ?, ?, ?, datetime('now'))`)
+		`INSERT INTO matches (id, user_id, other_id, reason, prompt_versions, correlation_id, compatibility, explanation, status, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'pending', datetime('now'), datetime(?))
+		 ON CONFLICT (user_id, other_id) DO UPDATE
+		 SET id = excluded.id, reason = excluded.reason, prompt_versions = excluded.prompt_versions,
+		     correlation_id = excluded.correlation_id, compatibility = excluded.compatibility, explanation = excluded.explanation,
+		     status = excluded.status,
+		     created_at = excluded.created_at, expires_at = excluded.expires_at
+		 WHERE matches.status = 'expired'`)
 	if err != nil {
 		return nil, err
 	}
 
 	id := uuid.New().String()
 
-	_, err = stmt.Exec(id, a.UserId, a.OtherId, a.Reason)
-	if err != nil {
-		return nil, err
-	}
+	for i, match := range []CreateMatch{a, b} {
+		matchId := id
+		if i > 0 {
+			matchId = uuid.New().String()
+		}
 
-	_, err = stmt.Exec(uuid.New(), b.UserId, b.OtherId, b.Reason)
-	if err != nil {
-		return nil, err
+		result, err := stmt.Exec(matchId, match.UserId, match.OtherId, match.Reason, match.PromptVersions, match.CorrelationId, match.Compatibility, match.Explanation, match.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		if inserted, err := result.RowsAffected(); err != nil || inserted != 1 {
+			return nil, ErrMatchExists
+		}
 	}
 
 	return &id, tx.Commit()
@@ -90,9 +117,14 @@ func (store *MatchStore) GetAllNonMatchedUsers(id string) ([]User, error) {
 		 AND id NOT IN (
 			 SELECT other_id
 			 FROM matches
-			 WHERE user_id = ?
+			 WHERE user_id = ? AND status != 'expired'
+		 )
+		 AND id NOT IN (
+			 SELECT blocked_id FROM blocks WHERE blocker_id = ?
+			 UNION
+			 SELECT blocker_id FROM blocks WHERE blocked_id = ?
 		 )`,
-		id, id)
+		id, id, id, id)
 	if err != nil {
 		return nil, err
 	}
@@ -112,13 +144,13 @@ func (store *MatchStore) GetAllNonMatchedUsers(id string) ([]User, error) {
 
 func (store *MatchStore) GetMatch(id string) (Match, error) {
 	row := store.db.QueryRow(
-		`SELECT id, user_id, other_id, reason, created_at
+		`SELECT id, user_id, other_id, reason, prompt_versions, compatibility, explanation, status, created_at, expires_at
 		 FROM matches
 		 WHERE id = ?`,
 		id)
 
 	match := Match{}
-	if err := row.Scan(&match.Id, &match.UserId, &match.OtherId, &match.Reason, &match.CreatedAt); err != nil {
+	if err := row.Scan(&match.Id, &match.UserId, &match.OtherId, &match.Reason, &match.PromptVersions, &match.Compatibility, &match.Explanation, &match.Status, &match.CreatedAt, &match.ExpiresAt); err != nil {
 		return Match{}, err
 	}
 
@@ -132,7 +164,7 @@ func (store *MatchStore) GetMatchedUsers(id string) ([]User, error) {
 		 WHERE id IN (
 			 SELECT other_id
 			 FROM matches
-			 WHERE user_id = ?
+			 WHERE user_id = ? AND status = 'active'
 		 )`,
 		id)
 	if err != nil {
@@ -151,3 +183,106 @@ func (store *MatchStore) GetMatchedUsers(id string) ([]User, error) {
 
 	return users, nil
 }
+
+// TransitionMatch moves a match and its reverse direction to new statuses
+// chosen by transition from their current ones, in a single transaction.
+func (store *MatchStore) TransitionMatch(id string, transition func(status, otherStatus string) (string, string, error)) error {
+	return store.transitionMatch(id, transition, false)
+}
+
+// CloseMatch transitions a match like TransitionMatch and, in the same
+// transaction, archives the conversation between the two users.
+func (store *MatchStore) CloseMatch(id string, transition func(status, otherStatus string) (string, string, error)) error {
+	return store.transitionMatch(id, transition, true)
+}
+
+func (store *MatchStore) transitionMatch(id string, transition func(status, otherStatus string) (string, string, error), archive bool) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userId, otherId, status string
+	row := tx.QueryRow("SELECT user_id, other_id, status FROM matches WHERE id = ?", id)
+	if err := row.Scan(&userId, &otherId, &status); err != nil {
+		return err
+	}
+
+	var otherStatus string
+	row = tx.QueryRow("SELECT status FROM matches WHERE user_id = ? AND other_id = ?", otherId, userId)
+	if err := row.Scan(&otherStatus); err != nil {
+		return err
+	}
+
+	newStatus, newOtherStatus, err := transition(status, otherStatus)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("UPDATE matches SET status = ? WHERE id = ? AND status = ?", newStatus, id, status)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated != 1 {
+		return ErrMatchConflict
+	}
+
+	result, err = tx.Exec("UPDATE matches SET status = ? WHERE user_id = ? AND other_id = ? AND status = ?", newOtherStatus, otherId, userId, otherStatus)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated != 1 {
+		return ErrMatchConflict
+	}
+
+	if archive {
+		if err := archiveConversation(tx, userId, otherId); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (store *MatchStore) ExpireMatches() (int64, error) {
+	result, err := store.db.Exec(
+		`UPDATE matches
+		 SET status = 'expired'
+		 WHERE status IN ('pending', 'accepted') AND expires_at <= datetime('now')`)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+type UserPair struct {
+	UserId  string
+	OtherId string
+}
+
+// GetUnavailablePairs returns the pairs of users that cannot be matched
+// because they already have a live or answered match, or one blocked the
+// other.
+func (store *MatchStore) GetUnavailablePairs() ([]UserPair, error) {
+	rows, err := store.db.Query(
+		`SELECT user_id, other_id FROM matches WHERE status != 'expired'
+		 UNION
+		 SELECT blocker_id, blocked_id FROM blocks`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pairs := []UserPair{}
+	for rows.Next() {
+		pair := UserPair{}
+		if err := rows.Scan(&pair.UserId, &pair.OtherId); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
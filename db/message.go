@@ -52,7 +52,7 @@ func (store *MessageStore) GetRecentMessages(user1, user2 string, limit int) ([]
 	rows, err := store.db.Query(
 		`SELECT id, sender_id, receiver_id, message, created_at
 		 FROM messages
-		 WHERE (sender_id, receiver_id) IN ((?, ?), (?, ?))
+		 WHERE (sender_id, receiver_id) IN ((?, ?), (?, ?)) AND archived_at IS NULL
 		 ORDER BY created_at DESC
 		 LIMIT ?`,
 		user1, user2, user2, user1, limit)
@@ -78,7 +78,7 @@ func (store *MessageStore) GetNewMessages(user1, user2 string, after string, lim
 	rows, err := store.db.Query(
 		`SELECT id, sender_id, receiver_id, message, created_at
 		 FROM messages
-		 WHERE (sender_id, receiver_id) IN ((?, ?), (?, ?)) AND created_at > datetime(?)
+		 WHERE (sender_id, receiver_id) IN ((?, ?), (?, ?)) AND created_at > datetime(?) AND archived_at IS NULL
 		 ORDER BY created_at DESC
 		 LIMIT ?
 		 `,
@@ -140,3 +140,17 @@ func (store *MessageStore) GetRecentMessagesAllConversations(user string, limit
 
 	return userConversations, nil
 }
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func archiveConversation(db execer, user1, user2 string) error {
+	_, err := db.Exec(
+		`UPDATE messages
+		 SET archived_at = datetime('now')
+		 WHERE (sender_id, receiver_id) IN ((?, ?), (?, ?)) AND archived_at IS NULL`,
+		user1, user2, user2, user1)
+
+	return err
+}
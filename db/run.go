@@ -0,0 +1,81 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type MatchRunStore struct {
+	db *sql.DB
+}
+
+func NewMatchRunStore(db *sql.DB) MatchRunStore {
+	return MatchRunStore{db}
+}
+
+type MatchRun struct {
+	Id             string
+	MatchId        *string
+	UserId         string
+	CorrelationId  string
+	PoolSize       int
+	Weights        string
+	Candidates     string
+	ChosenId       string
+	Reasoning      string
+	Fallback       bool
+	Models         string
+	Timings        string
+	PromptVersions string
+	Outcome        string
+	Error          *string
+	CreatedAt      string
+}
+
+type CreateMatchRun struct {
+	MatchId        *string
+	UserId         string
+	CorrelationId  string
+	PoolSize       int
+	Weights        string
+	Candidates     string
+	ChosenId       string
+	Reasoning      string
+	Fallback       bool
+	Models         string
+	Timings        string
+	PromptVersions string
+	Outcome        string
+	Error          *string
+}
+
+func (store *MatchRunStore) CreateMatchRun(run CreateMatchRun) error {
+	_, err := store.db.Exec(
+		`INSERT INTO match_runs (id, match_id, user_id, correlation_id, pool_size, weights, candidates, chosen_id,
+		                         reasoning, fallback, models, timings, prompt_versions, outcome, error, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))`,
+		uuid.New().String(), run.MatchId, run.UserId, run.CorrelationId, run.PoolSize, run.Weights, run.Candidates, run.ChosenId,
+		run.Reasoning, run.Fallback, run.Models, run.Timings, run.PromptVersions, run.Outcome, run.Error)
+
+	return err
+}
+
+// GetMatchRun returns the run that generated a match, looked up by the ID of
+// either direction of the match.
+func (store *MatchRunStore) GetMatchRun(matchId string) (MatchRun, error) {
+	row := store.db.QueryRow(
+		`SELECT id, match_id, user_id, correlation_id, pool_size, weights, candidates, chosen_id,
+		        reasoning, fallback, models, timings, prompt_versions, outcome, error, created_at
+		 FROM match_runs
+		 WHERE correlation_id = (SELECT correlation_id FROM matches WHERE id = ?)`,
+		matchId)
+
+	run := MatchRun{}
+	if err := row.Scan(&run.Id, &run.MatchId, &run.UserId, &run.CorrelationId, &run.PoolSize, &run.Weights, &run.Candidates, &run.ChosenId,
+		&run.Reasoning, &run.Fallback, &run.Models, &run.Timings, &run.PromptVersions, &run.Outcome, &run.Error, &run.CreatedAt); err != nil {
+		return MatchRun{}, err
+	}
+
+	return run, nil
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+type UsageStore struct {
+	db *sql.DB
+}
+
+func NewUsageStore(db *sql.DB) UsageStore {
+	return UsageStore{db}
+}
+
+type CreateUsage struct {
+	UserId       string
+	Feature      string
+	Model        string
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+	LatencyMs    int64
+}
+
+func (store *UsageStore) CreateUsage(usage CreateUsage) error {
+	_, err := store.db.Exec(
+		`INSERT INTO llm_usage (id, user_id, feature, model, input_tokens, output_tokens, cost, latency_ms, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))`,
+		uuid.New().String(), usage.UserId, usage.Feature, usage.Model, usage.InputTokens, usage.OutputTokens,
+		usage.Cost, usage.LatencyMs)
+
+	return err
+}
+
+func (store *UsageStore) GetUserMonthlyCost(userId string) (float64, error) {
+	row := store.db.QueryRow(
+		`SELECT COALESCE(SUM(cost), 0)
+		 FROM llm_usage
+		 WHERE user_id = ? AND created_at >= datetime('now', 'start of month')`,
+		userId)
+
+	var cost float64
+	if err := row.Scan(&cost); err != nil {
+		return 0, err
+	}
+
+	return cost, nil
+}
+
+type UsageSummary struct {
+	Key          string
+	Calls        int
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+	LatencyMs    float64
+}
+
+var ErrInvalidGrouping = errors.New("invalid usage grouping")
+
+var usageGroupings = map[string]string{
+	"day":     "date(created_at)",
+	"feature": "feature",
+	"user":    "user_id",
+	"model":   "model",
+}
+
+func (store *UsageStore) GetUsageSummary(groupBy string, since string) ([]UsageSummary, error) {
+	column, ok := usageGroupings[groupBy]
+	if !ok {
+		return nil, ErrInvalidGrouping
+	}
+
+	rows, err := store.db.Query(
+		`SELECT `+column+`, COUNT(*), SUM(input_tokens), SUM(output_tokens), SUM(cost), AVG(latency_ms)
+		 FROM llm_usage
+		 WHERE created_at >= datetime(?)
+		 GROUP BY `+column+`
+		 ORDER BY SUM(cost) DESC`,
+		since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []UsageSummary{}
+	for rows.Next() {
+		summary := UsageSummary{}
+		if err := rows.Scan(&summary.Key, &summary.Calls, &summary.InputTokens, &summary.OutputTokens,
+			&summary.Cost, &summary.LatencyMs); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
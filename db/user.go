@@ -82,14 +82,23 @@ func (store *UserStore) UpdateUser(user User) error {
 	return err
 }
 
-func (store *UserStore) UpdateUserProfile(id string, profile string) error {
-	_, err := store.db.Exec("UPDATE users SET profile = ?, generated_at = datetime('now') WHERE id = ?", profile, id)
+func (store *UserStore) UpdateUserProfile(id string, profile string, promptVersions string, correlationId string) error {
+	_, err := store.db.Exec("UPDATE users SET profile = ?, prompt_versions = ?, profile_correlation_id = ?, generated_at = datetime('now') WHERE id = ?", profile, promptVersions, correlationId, id)
 
 	return err
 }
 
-func (store *UserStore) GetAllUsers() ([]User, error) {
-	rows, err := store.db.Query("SELECT id, name, updated_at, profile, generated_at FROM users")
+// GetAllUsers excludes users who have blocked or been blocked by viewerId.
+func (store *UserStore) GetAllUsers(viewerId string) ([]User, error) {
+	rows, err := store.db.Query(
+		`SELECT id, name, updated_at, profile, generated_at
+		 FROM users
+		 WHERE id NOT IN (
+			 SELECT blocked_id FROM blocks WHERE blocker_id = ?
+			 UNION
+			 SELECT blocker_id FROM blocks WHERE blocked_id = ?
+		 )`,
+		viewerId, viewerId)
 	if err != nil {
 		return nil, err
 	}
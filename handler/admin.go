@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (handler *Handler) GetUsage(c echo.Context) error {
+	groupBy := c.QueryParam("group_by")
+	if groupBy == "" {
+		groupBy = "day"
+	}
+
+	since := time.Now().AddDate(0, 0, -30)
+	if param := c.QueryParam("since"); param != "" {
+		parsed, err := time.Parse(time.DateOnly, param)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "since must be a YYYY-MM-DD date")
+		}
+		since = parsed
+	}
+
+	summaries, err := handler.usageService.GetUsageSummary(groupBy, since)
+	if err != nil {
+		if err == db.ErrInvalidGrouping {
+			return echo.NewHTTPError(http.StatusBadRequest, "group_by must be one of day, feature, user, model")
+		}
+		fmt.Println("Error getting usage", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "error getting usage")
+	}
+
+	return c.JSON(http.StatusOK, summaries)
+}
+
+func (handler *Handler) GetCalls(c echo.Context) error {
+	var calls []model.LlmCall
+	var err error
+
+	switch {
+	case c.QueryParam("correlation_id") != "":
+		calls, err = handler.callService.GetCorrelatedCalls(c.QueryParam("correlation_id"))
+	case c.QueryParam("match_id") != "":
+		calls, err = handler.callService.GetMatchCalls(c.QueryParam("match_id"))
+	case c.QueryParam("user_id") != "":
+		limit := 100
+		if param := c.QueryParam("limit"); param != "" {
+			limit, err = strconv.Atoi(param)
+			if err != nil || limit <= 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "limit must be a positive integer")
+			}
+		}
+		calls, err = handler.callService.GetUserCalls(c.QueryParam("user_id"), limit)
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "one of user_id, match_id or correlation_id is required")
+	}
+
+	if err != nil {
+		fmt.Println("Error getting llm calls", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "error getting llm calls")
+	}
+
+	return c.JSON(http.StatusOK, calls)
+}
+
+func (handler *Handler) GetMatchRun(c echo.Context) error {
+	run, err := handler.matchService.GetMatchRun(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "no run recorded for match")
+		}
+		fmt.Println("Error getting match run", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "error getting match run")
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+func (handler *Handler) RunBatchMatch(c echo.Context) error {
+	perUser := 1
+	if param := c.QueryParam("per_user"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "per_user must be a positive integer")
+		}
+		perUser = parsed
+	}
+
+	dryRun := false
+	if param := c.QueryParam("dry_run"); param != "" {
+		parsed, err := strconv.ParseBool(param)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "dry_run must be true or false")
+		}
+		dryRun = parsed
+	}
+
+	run, err := handler.matchService.RunBatchMatch(c.Request().Context(), perUser, dryRun)
+	if err != nil {
+		fmt.Println("Error running batch match", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "error running batch match")
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+func (handler *Handler) GetFeedbackReport(c echo.Context) error {
+	report, err := handler.feedbackService.GetFeedbackReport()
+	if err != nil {
+		fmt.Println("Error getting feedback report", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "error getting feedback report")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
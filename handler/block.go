@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/nvdaz/find-a-friend-api/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type BlockUserRequest struct {
+	BlockedId string `json:"blocked_id"`
+}
+
+func (handler *Handler) BlockUser(c echo.Context) error {
+	request := BlockUserRequest{}
+	if err := c.Bind(&request); err != nil || request.BlockedId == "" {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "error parsing request body")
+	}
+
+	err := handler.blockService.BlockUser(c.Param("id"), request.BlockedId)
+	if errors.Is(err, service.ErrSelfBlock) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		fmt.Println("Error blocking user", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "error blocking user")
+	}
+
+	return c.NoContent(http.StatusCreated)
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (handler *Handler) GetGroup(c echo.Context) error {
+	group, err := handler.groupService.GetGroup(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "group not found")
+		}
+		fmt.Println("Error getting group", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, group)
+}
+
+func (handler *Handler) GetUserGroups(c echo.Context) error {
+	groups, err := handler.groupService.GetUserGroups(c.Param("id"))
+	if err != nil {
+		fmt.Println("Error getting user groups", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, groups)
+}
+
+func (handler *Handler) FormUserGroup(c echo.Context) error {
+	group, err := handler.groupService.FormUserGroup(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		switch {
+		case err == db.ErrUserNotFound:
+			return echo.NewHTTPError(http.StatusNotFound, "user not found")
+		case errors.Is(err, service.ErrNoGroup):
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		case errors.Is(err, llm.ErrBudgetExceeded):
+			return echo.NewHTTPError(http.StatusTooManyRequests, "llm budget exceeded")
+		}
+		fmt.Println("Error forming group", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusCreated, group)
+}
+
+func (handler *Handler) RunGroupBatch(c echo.Context) error {
+	dryRun := false
+	if param := c.QueryParam("dry_run"); param != "" {
+		parsed, err := strconv.ParseBool(param)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "dry_run must be true or false")
+		}
+		dryRun = parsed
+	}
+
+	run, err := handler.groupService.RunGroupBatch(c.Request().Context(), dryRun)
+	if err != nil {
+		fmt.Println("Error running group batch", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "error running group batch")
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
@@ -5,11 +5,17 @@ import (
 )
 
 type Handler struct {
-	userService    service.UserService
-	matchService   service.MatchService
-	messageService service.MessageService
+	userService     service.UserService
+	matchService    service.MatchService
+	messageService  service.MessageService
+	usageService    service.UsageService
+	callService     service.CallService
+	blockService    service.BlockService
+	groupService    service.GroupService
+	feedbackService service.FeedbackService
+	sessionService  service.SessionService
 }
 
-func NewHandler(userService service.UserService, matchService service.MatchService, messageService service.MessageService) *Handler {
-	return &Handler{userService, matchService, messageService}
+func NewHandler(userService service.UserService, matchService service.MatchService, messageService service.MessageService, usageService service.UsageService, callService service.CallService, blockService service.BlockService, groupService service.GroupService, feedbackService service.FeedbackService, sessionService service.SessionService) *Handler {
+	return &Handler{userService, matchService, messageService, usageService, callService, blockService, groupService, feedbackService, sessionService}
 }
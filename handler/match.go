@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/model"
+	"github.com/nvdaz/find-a-friend-api/service"
+
 	"github.com/labstack/echo/v4"
 )
 
@@ -20,8 +26,11 @@ func (handler *Handler) GetUserMatches(c echo.Context) error {
 
 func (handler *Handler) GenerateUserMatch(c echo.Context) error {
 	id := c.Param("id")
-	match, err := handler.matchService.GenerateUserMatch(id)
+	match, err := handler.matchService.GenerateUserMatch(c.Request().Context(), id)
 	if err != nil {
+		if errors.Is(err, llm.ErrBudgetExceeded) {
+			return echo.NewHTTPError(http.StatusTooManyRequests, "llm budget exceeded")
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, nil)
 	}
 
@@ -37,3 +46,66 @@ func (handler *Handler) GetMatch(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, match)
 }
+
+func (handler *Handler) AcceptMatch(c echo.Context) error {
+	return handler.transitionMatch(c, handler.matchService.AcceptMatch)
+}
+
+func (handler *Handler) DeclineMatch(c echo.Context) error {
+	return handler.transitionMatch(c, handler.matchService.DeclineMatch)
+}
+
+func (handler *Handler) transitionMatch(c echo.Context, transition func(id string) (model.Match, error)) error {
+	id := c.Param("id")
+	match, err := transition(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return echo.NewHTTPError(http.StatusNotFound, "match not found")
+		case errors.Is(err, service.ErrInvalidTransition):
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		fmt.Println("Error updating match status", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, match)
+}
+
+func (handler *Handler) Unmatch(c echo.Context) error {
+	id := c.Param("id")
+	if err := handler.matchService.Unmatch(id); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return echo.NewHTTPError(http.StatusNotFound, "match not found")
+		case errors.Is(err, service.ErrInvalidTransition):
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		fmt.Println("Error unmatching", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, nil)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (handler *Handler) RateMatch(c echo.Context) error {
+	feedback := model.MatchFeedback{}
+	if err := c.Bind(&feedback); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "error parsing request body")
+	}
+
+	if err := handler.feedbackService.RateMatch(c.Param("id"), feedback); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return echo.NewHTTPError(http.StatusNotFound, "match not found")
+		case errors.Is(err, service.ErrInvalidFeedback):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrMatchNotRatable):
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		fmt.Println("Error rating match", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, nil)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
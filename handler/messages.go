@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/nvdaz/find-a-friend-api/service"
+
 	"github.com/labstack/echo/v4"
 )
 
@@ -21,6 +24,9 @@ func (handler *Handler) CreateMessage(c echo.Context) error {
 
 	err := handler.messageService.CreateMessage(request.SenderId, request.ReceiverId, request.Message)
 
+	if errors.Is(err, service.ErrBlocked) {
+		return echo.NewHTTPError(http.StatusForbidden, "receiver has blocked the sender")
+	}
 	if err != nil {
 		fmt.Println("Error creating message", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "error creating message")
@@ -1,30 +1,50 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/model"
 	"github.com/nvdaz/find-a-friend-api/service"
 
 	"github.com/labstack/echo/v4"
 )
 
 func (handler *Handler) GetUser(c echo.Context) error {
-	user, err := handler.userService.GetUser(c.Param("id"))
+	user, err := handler.userService.GetUser(c.Request().Context(), c.Param("id"))
 	if err != nil {
 		fmt.Println("Error getting user", err)
 		if err == db.ErrUserNotFound {
 			return c.JSON(http.StatusNotFound, nil)
 		}
+		if errors.Is(err, llm.ErrBudgetExceeded) {
+			return c.JSON(http.StatusTooManyRequests, nil)
+		}
 
 		return c.JSON(http.StatusInternalServerError, nil)
 	}
 	return c.JSON(http.StatusOK, user)
 }
 
+// Authenticate validates the session token a user got from registering or
+// logging in, for use with middleware.KeyAuth, and makes its user the viewer.
+func (handler *Handler) Authenticate(token string, c echo.Context) (bool, error) {
+	userId, err := handler.sessionService.Verify(token)
+	if err != nil {
+		return false, nil
+	}
+
+	c.Set("viewer_id", userId)
+	return true, nil
+}
+
+// GetAllUsers lists users for the authenticated viewer, leaving out anyone
+// either of them has blocked.
 func (handler *Handler) GetAllUsers(c echo.Context) error {
-	users, err := handler.userService.GetAllUsers()
+	users, err := handler.userService.GetAllUsers(c.Get("viewer_id").(string))
 	if err != nil {
 		fmt.Println("Error getting users", err)
 		return c.JSON(http.StatusInternalServerError, nil)
@@ -52,6 +72,13 @@ func (handler *Handler) UpdateUser(c echo.Context) error {
 	return c.NoContent(http.StatusNotImplemented)
 }
 
+// SessionResponse is the user who registered or logged in and the token that
+// authenticates their later requests.
+type SessionResponse struct {
+	*model.User
+	Token string `json:"token"`
+}
+
 type RegisterUserRequest struct {
 	Name     string `json:"name"`
 	Username string `json:"username"`
@@ -70,7 +97,7 @@ func (handler *Handler) RegisterUser(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "error registering user")
 	}
 
-	return c.JSON(http.StatusOK, user)
+	return c.JSON(http.StatusOK, SessionResponse{user, handler.sessionService.Issue(user.Id)})
 }
 
 type LoginUserRequest struct {
@@ -90,7 +117,7 @@ func (handler *Handler) LoginUser(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "error logging in user")
 	}
 
-	return c.JSON(http.StatusOK, user)
+	return c.JSON(http.StatusOK, SessionResponse{user, handler.sessionService.Issue(user.Id)})
 }
 
 type UpdateUserIconRequest struct {
@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const anthropicMaxTokens = 4096
+
+var anthropicModels = map[Model]string{
+	ModelGpt4:         "claude-3-sonnet-20240229",
+	ModelGpt3p5:       "claude-3-haiku-20240307",
+	ModelClaudeHaiku:  "claude-3-haiku-20240307",
+	ModelClaudeSonnet: "claude-3-sonnet-20240229",
+}
+
+type AnthropicProvider struct {
+	baseUrl string
+	apiKey  string
+	client  *http.Client
+}
+
+func NewAnthropicProvider(baseUrl, apiKey string) *AnthropicProvider {
+	if baseUrl == "" {
+		baseUrl = "https://api.anthropic.com"
+	}
+
+	return &AnthropicProvider{strings.TrimSuffix(baseUrl, "/"), apiKey, &http.Client{}}
+}
+
+func (provider *AnthropicProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	maxTokens := request.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = anthropicMaxTokens
+	}
+
+	// Anthropic has no JSON mode, so the reply is prefilled with an opening
+	// brace instead.
+	messages := request.Turns()
+	prefill := ""
+	if request.Json && len(messages) > 0 && messages[len(messages)-1].Role == RoleUser {
+		prefill = "{"
+		messages = append(messages, AssistantMessage(prefill))
+	}
+
+	body := struct {
+		Model         string    `json:"model"`
+		System        string    `json:"system,omitempty"`
+		Messages      []Message `json:"messages"`
+		MaxTokens     int       `json:"max_tokens"`
+		Temperature   *float64  `json:"temperature,omitempty"`
+		StopSequences []string  `json:"stop_sequences,omitempty"`
+	}{
+		Model:         mapModel(anthropicModels, request.Model),
+		System:        request.System(),
+		Messages:      messages,
+		MaxTokens:     maxTokens,
+		Temperature:   request.Temperature,
+		StopSequences: request.Stop,
+	}
+
+	headers := map[string]string{
+		"x-api-key":         provider.apiKey,
+		"anthropic-version": "2023-06-01",
+	}
+
+	var response struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := postJson(ctx, provider.client, provider.baseUrl+"/v1/messages", headers, body, &response); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	text.WriteString(prefill)
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	if text.Len() == len(prefill) {
+		return nil, fmt.Errorf("no text content in response")
+	}
+
+	return &Response{
+		Text: text.String(),
+		Usage: Usage{
+			InputTokens:  response.Usage.InputTokens,
+			OutputTokens: response.Usage.OutputTokens,
+		},
+	}, nil
+}
@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+type correlationKey struct{}
+
+// A correlation ID ties together every call made on behalf of one unit of work,
+// such as a profile generation run or a match generation.
+func WithCorrelationId(ctx context.Context, correlationId string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, correlationId)
+}
+
+func CorrelationIdFrom(ctx context.Context) string {
+	correlationId, _ := ctx.Value(correlationKey{}).(string)
+	return correlationId
+}
+
+const (
+	OutcomeOk              = "ok"
+	OutcomeRequestError    = "request_error"
+	OutcomeParseError      = "parse_error"
+	OutcomeValidationError = "validation_error"
+)
+
+type CallRecord struct {
+	CorrelationId string
+	UserId        string
+	Caller        string
+	Model         Model
+	System        string
+	Prompt        string
+	Response      *string
+	Outcome       string
+	Strategy      string
+	Error         *string
+	Step          int
+	Retry         int
+	Latency       time.Duration
+}
+
+type CallStore interface {
+	RecordCall(record CallRecord) error
+}
+
+var redactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[email]"},
+	{regexp.MustCompile(`https?://\S+`), "[url]"},
+	// International numbers start with +; others need an area code, so dates,
+	// times and ranges are left alone.
+	{regexp.MustCompile(`\+\d{1,3}(?:[\s.-]?(?:\(\d{1,4}\)|\d{1,4})){2,5}`), "[phone]"},
+	{regexp.MustCompile(`(?:\(\d{3}\)\s?|\b\d{3}[\s.-])\d{3}[\s.-]\d{4}\b`), "[phone]"},
+}
+
+func Redact(text string) string {
+	for _, redaction := range redactions {
+		text = redaction.pattern.ReplaceAllString(text, redaction.replacement)
+	}
+
+	return text
+}
+
+func outcomeOf(err error) string {
+	switch err.(type) {
+	case nil:
+		return OutcomeOk
+	case *ValidationError:
+		return OutcomeValidationError
+	default:
+		return OutcomeParseError
+	}
+}
+
+// A ModelRecorder collects which models answered each feature's calls, so a
+// run can report the models it ended up using after fallbacks.
+type ModelRecorder struct {
+	mu     sync.Mutex
+	models map[string]map[Model]bool
+}
+
+func NewModelRecorder() *ModelRecorder {
+	return &ModelRecorder{models: map[string]map[Model]bool{}}
+}
+
+type modelRecorderKey struct{}
+
+func WithModelRecorder(ctx context.Context, recorder *ModelRecorder) context.Context {
+	return context.WithValue(ctx, modelRecorderKey{}, recorder)
+}
+
+func (recorder *ModelRecorder) record(feature string, model Model) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if recorder.models[feature] == nil {
+		recorder.models[feature] = map[Model]bool{}
+	}
+	recorder.models[feature][model] = true
+}
+
+func (recorder *ModelRecorder) Models() map[string][]string {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	models := make(map[string][]string, len(recorder.models))
+	for feature, used := range recorder.models {
+		for model := range used {
+			models[feature] = append(models[feature], model.String())
+		}
+		sort.Strings(models[feature])
+	}
+
+	return models
+}
+
+func (client *Client) recordCall(ctx context.Context, record CallRecord) {
+	if recorder, ok := ctx.Value(modelRecorderKey{}).(*ModelRecorder); ok && record.Outcome == OutcomeOk {
+		recorder.record(FeatureFrom(ctx), record.Model)
+	}
+
+	if client.calls == nil {
+		return
+	}
+
+	record.CorrelationId = CorrelationIdFrom(ctx)
+	record.UserId = UserFrom(ctx)
+	record.Caller = FeatureFrom(ctx)
+	record.System = Redact(record.System)
+	record.Prompt = Redact(record.Prompt)
+
+	if err := client.calls.RecordCall(record); err != nil {
+		log.Println("Error recording llm call", err)
+	}
+}
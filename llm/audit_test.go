@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"mail me at sam.lee+friends@example.co.uk", "mail me at [email]"},
+		{"see https://example.com/profile?id=3 for more", "see [url] for more"},
+		{"call +1 (555) 123-4567 tonight", "call [phone] tonight"},
+		{"my number is +44 20 7946 0958", "my number is [phone]"},
+		{"text +15551234567", "text [phone]"},
+		{"call (555) 123-4567", "call [phone]"},
+		{"call 555-123-4567 or 555.123.4567", "call [phone] or [phone]"},
+		{"free on 2024-05-01 10:30", "free on 2024-05-01 10:30"},
+		{"meet 10:30-11:45 on 01/05/2024", "meet 10:30-11:45 on 01/05/2024"},
+		{"lived there 1999-2004 (5 years)", "lived there 1999-2004 (5 years)"},
+		{"ran 100-200 km a month", "ran 100-200 km a month"},
+		{"at 2024-05-01T10:30:00Z", "at 2024-05-01T10:30:00Z"},
+		{"order 12345678 shipped", "order 12345678 shipped"},
+	}
+
+	for _, c := range cases {
+		if got := Redact(c.text); got != c.want {
+			t.Errorf("Redact(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+type recordedCalls []CallRecord
+
+func (calls *recordedCalls) RecordCall(record CallRecord) error {
+	*calls = append(*calls, record)
+	return nil
+}
+
+func TestRunToolsRecordsStepsAndRetries(t *testing.T) {
+	provider := &cannedProvider{responses: []string{
+		`{"tool": "lookup", "arguments": {}}`,
+		`{"tool": "unknown"}`,
+		`{"tool": "lookup", "arguments": {}}`,
+		`{"answer": {"goals": []}}`,
+	}}
+	calls := recordedCalls{}
+	client := NewClient(provider, nil, &calls)
+	lookup := NewTool("lookup", "Looks something up.", func(ctx context.Context, arguments struct{}) (string, error) {
+		return "found", nil
+	})
+
+	var result testGoals
+	err := client.RunTools(context.Background(), &result, Chain{"test-tools-model"}, []Message{UserMessage("goals please")}, []Tool{lookup}, 10, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := [][2]int{{0, 0}, {1, 0}, {1, 1}, {2, 0}}
+	if len(calls) != len(want) {
+		t.Fatalf("recorded %d calls, want %d", len(calls), len(want))
+	}
+	for i, call := range calls {
+		if got := [2]int{call.Step, call.Retry}; got != want[i] {
+			t.Errorf("call %d recorded step, retry %v, want %v", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+const (
+	BreakerThreshold = 5
+	BreakerCooldown  = 30 * time.Second
+)
+
+type breakerState string
+
+const (
+	breakerClosed   breakerState = "closed"
+	breakerOpen     breakerState = "open"
+	breakerHalfOpen breakerState = "half-open"
+)
+
+type breaker struct {
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < BreakerCooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == breakerHalfOpen || b.failures >= BreakerThreshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+type breakerRegistry struct {
+	mu       sync.Mutex
+	breakers map[Model]*breaker
+}
+
+func (registry *breakerRegistry) get(model Model) *breaker {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	b, ok := registry.breakers[model]
+	if !ok {
+		b = &breaker{state: breakerClosed}
+		registry.breakers[model] = b
+	}
+
+	return b
+}
+
+func (registry *breakerRegistry) snapshot() any {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	type breakerStatus struct {
+		State    breakerState `json:"state"`
+		Failures int          `json:"failures"`
+		OpenedAt *time.Time   `json:"opened_at,omitempty"`
+	}
+
+	statuses := map[Model]breakerStatus{}
+	for model, b := range registry.breakers {
+		b.mu.Lock()
+		status := breakerStatus{State: b.state, Failures: b.failures}
+		if b.state != breakerClosed {
+			openedAt := b.openedAt
+			status.OpenedAt = &openedAt
+		}
+		b.mu.Unlock()
+		statuses[model] = status
+	}
+
+	return statuses
+}
+
+var circuitBreakers = &breakerRegistry{breakers: map[Model]*breaker{}}
+
+func init() {
+	expvar.Publish("llm_breakers", expvar.Func(circuitBreakers.snapshot))
+}
@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultCacheTTL        = 7 * 24 * time.Hour
+	DefaultCacheMaxEntries = 10000
+)
+
+type CacheStore interface {
+	GetCachedResponse(key string) (*string, error)
+	PutCachedResponse(key, response string, ttl time.Duration, maxEntries int) error
+	DeleteCachedResponse(key string) error
+}
+
+type cacheBypassKey struct{}
+
+// WithoutCache skips cached replies, as retries need a fresh answer. Fresh
+// replies are still written, so a corrected reply replaces a bad one.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// A pendingCache holds back the cache write for a reply until the caller has
+// parsed it, so only replies that parse are cached and cached replies that no
+// longer parse are evicted.
+type pendingCache struct {
+	keep    func()
+	discard func()
+}
+
+type pendingCacheKey struct{}
+
+func withPendingCache(ctx context.Context) (context.Context, *pendingCache) {
+	pending := &pendingCache{}
+	return context.WithValue(ctx, pendingCacheKey{}, pending), pending
+}
+
+func (pending *pendingCache) settle(parsed bool) {
+	if parsed && pending.keep != nil {
+		pending.keep()
+	}
+	if !parsed && pending.discard != nil {
+		pending.discard()
+	}
+}
+
+func cacheKey(request Request) string {
+	temperature := "default"
+	if request.Temperature != nil {
+		temperature = strconv.FormatFloat(*request.Temperature, 'g', -1, 64)
+	}
+
+	parts := []string{request.Model.String(), temperature, strconv.Itoa(request.MaxTokens), strconv.FormatBool(request.Json), strconv.Itoa(len(request.Stop))}
+	parts = append(parts, request.Stop...)
+	for _, message := range request.Messages {
+		parts = append(parts, string(message.Role), message.Content)
+	}
+
+	hash := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(hash, "%d:%s", len(part), part)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type CachingProvider struct {
+	provider   Provider
+	store      CacheStore
+	ttl        time.Duration
+	maxEntries int
+}
+
+func NewCachingProvider(provider Provider, store CacheStore, ttl time.Duration, maxEntries int) *CachingProvider {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheMaxEntries
+	}
+
+	return &CachingProvider{provider, store, ttl, maxEntries}
+}
+
+func NewCachingProviderFromEnv(provider Provider, store CacheStore) Provider {
+	if disabled, _ := strconv.ParseBool(os.Getenv("LLM_CACHE_DISABLED")); disabled {
+		return provider
+	}
+
+	ttl, _ := time.ParseDuration(os.Getenv("LLM_CACHE_TTL"))
+	maxEntries, _ := strconv.Atoi(os.Getenv("LLM_CACHE_MAX_ENTRIES"))
+
+	return NewCachingProvider(provider, store, ttl, maxEntries)
+}
+
+func (provider *CachingProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	key := cacheKey(request)
+	pending, _ := ctx.Value(pendingCacheKey{}).(*pendingCache)
+
+	if !cacheBypassed(ctx) {
+		cached, err := provider.store.GetCachedResponse(key)
+		if err != nil {
+			log.Println("Error reading llm cache", err)
+		} else if cached != nil {
+			if pending != nil {
+				pending.discard = func() {
+					if err := provider.store.DeleteCachedResponse(key); err != nil {
+						log.Println("Error evicting llm cache", err)
+					}
+				}
+			}
+			return &Response{Text: *cached}, nil
+		}
+	}
+
+	response, err := provider.provider.Complete(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	write := func() {
+		if err := provider.store.PutCachedResponse(key, response.Text, provider.ttl, provider.maxEntries); err != nil {
+			log.Println("Error writing llm cache", err)
+		}
+	}
+	if pending != nil {
+		pending.keep = write
+	} else {
+		write()
+	}
+
+	return response, nil
+}
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type memoryCache map[string]string
+
+func (cache memoryCache) GetCachedResponse(key string) (*string, error) {
+	if response, ok := cache[key]; ok {
+		return &response, nil
+	}
+	return nil, nil
+}
+
+func (cache memoryCache) PutCachedResponse(key, response string, ttl time.Duration, maxEntries int) error {
+	cache[key] = response
+	return nil
+}
+
+func (cache memoryCache) DeleteCachedResponse(key string) error {
+	delete(cache, key)
+	return nil
+}
+
+type cannedProvider struct {
+	responses []string
+	calls     int
+}
+
+func (provider *cannedProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	response := provider.responses[provider.calls%len(provider.responses)]
+	provider.calls++
+	return &Response{Text: response}, nil
+}
+
+const testCacheModel Model = "test-cache-model"
+
+func TestCacheKeepsOnlyParsedReplies(t *testing.T) {
+	cache := memoryCache{}
+	inner := &cannedProvider{responses: []string{`{"goals": "soon"}`, `{"goals": []}`}}
+	client := NewClient(NewCachingProvider(inner, cache, 0, 0), nil, nil)
+	messages := []Message{UserMessage("goals please")}
+
+	var result testGoals
+	if err := client.ChatJson(context.Background(), &result, Chain{testCacheModel}, messages, Options{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cache) != 1 {
+		t.Fatalf("got %d cache entries, want only the corrected reply", len(cache))
+	}
+	for _, response := range cache {
+		if response != `{"goals": []}` {
+			t.Fatalf("cached %q", response)
+		}
+	}
+}
+
+func TestCacheEvictsRepliesThatNoLongerParse(t *testing.T) {
+	cache := memoryCache{}
+	inner := &cannedProvider{responses: []string{`{"goals": []}`}}
+	client := NewClient(NewCachingProvider(inner, cache, 0, 0), nil, nil)
+	messages := []Message{UserMessage("goals please")}
+
+	schema := SchemaFor(&testGoals{})
+	request := Request{Model: testCacheModel, Messages: withSystem(messages, "The JSON object must conform to this JSON Schema:\n"+schema.String())}
+	key := cacheKey(request)
+	cache[key] = "not json"
+
+	var result testGoals
+	if err := client.ChatJson(context.Background(), &result, Chain{testCacheModel}, messages, Options{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cache[key] == "not json" {
+		t.Fatal("malformed cached reply was not evicted")
+	}
+
+	inner.calls = 0
+	if err := client.ChatJson(context.Background(), &result, Chain{testCacheModel}, messages, Options{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if inner.calls != 0 {
+		t.Fatalf("second call reached the provider %d times, want it served from cache", inner.calls)
+	}
+}
@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+var ErrCassetteMiss = errors.New("no cassette entry for request")
+
+type cassetteEntry struct {
+	Request
+	Response string `json:"response"`
+}
+
+func (entry *cassetteEntry) matches(request Request) bool {
+	return cacheKey(entry.Request) == cacheKey(request)
+}
+
+type Cassette struct {
+	path    string
+	mu      sync.Mutex
+	entries []cassetteEntry
+	played  map[int]bool
+}
+
+// LoadCassette reads a recorded cassette for replay. A missing file is an
+// error, since replaying it would miss every request.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load cassette: %w", err)
+	}
+
+	cassette := &Cassette{path: path, played: map[int]bool{}}
+	if err := json.Unmarshal(data, &cassette.entries); err != nil {
+		return nil, fmt.Errorf("unmarshal cassette: %w", err)
+	}
+
+	return cassette, nil
+}
+
+// OpenCassette loads a cassette to record into, starting an empty one when
+// the file does not exist yet.
+func OpenCassette(path string) (*Cassette, error) {
+	cassette, err := LoadCassette(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Cassette{path: path, played: map[int]bool{}}, nil
+	}
+
+	return cassette, err
+}
+
+func (cassette *Cassette) record(request Request, response string) error {
+	cassette.mu.Lock()
+	defer cassette.mu.Unlock()
+
+	cassette.entries = append(cassette.entries, cassetteEntry{request, response})
+
+	data, err := json.MarshalIndent(cassette.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(cassette.path, data, 0644)
+}
+
+// Identical requests are served in recorded order, repeating the last match
+// once every recording has been played.
+func (cassette *Cassette) replay(request Request) (string, error) {
+	cassette.mu.Lock()
+	defer cassette.mu.Unlock()
+
+	last := -1
+	for i := range cassette.entries {
+		if !cassette.entries[i].matches(request) {
+			continue
+		}
+
+		last = i
+		if !cassette.played[i] {
+			cassette.played[i] = true
+			return cassette.entries[i].Response, nil
+		}
+	}
+
+	if last == -1 {
+		return "", fmt.Errorf("%w: model %s, system %.60q", ErrCassetteMiss, request.Model, request.System())
+	}
+
+	return cassette.entries[last].Response, nil
+}
+
+type RecordingProvider struct {
+	provider Provider
+	cassette *Cassette
+}
+
+func NewRecordingProvider(provider Provider, cassette *Cassette) *RecordingProvider {
+	return &RecordingProvider{provider, cassette}
+}
+
+func (provider *RecordingProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	response, err := provider.provider.Complete(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := provider.cassette.record(request, response.Text); err != nil {
+		return nil, fmt.Errorf("record cassette: %w", err)
+	}
+
+	return response, nil
+}
+
+type ReplayProvider struct {
+	cassette *Cassette
+}
+
+func NewReplayProvider(cassette *Cassette) *ReplayProvider {
+	return &ReplayProvider{cassette}
+}
+
+func (provider *ReplayProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	text, err := provider.cassette.replay(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{Text: text}, nil
+}
@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadCassetteMissingFile(t *testing.T) {
+	_, err := LoadCassette(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got %v, want a not exist error", err)
+	}
+}
+
+func TestReplayMissingCassette(t *testing.T) {
+	t.Setenv("LLM_CASSETTE_MODE", CassetteReplay)
+	t.Setenv("LLM_CASSETTE_PATH", filepath.Join(t.TempDir(), "missing.json"))
+
+	if _, err := NewProviderFromEnv(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got %v, want a not exist error", err)
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recording, err := OpenCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inner := &cannedProvider{responses: []string{"first", "second"}}
+	recorder := NewRecordingProvider(inner, recording)
+	request := Request{Model: "test-cassette-model", Messages: []Message{UserMessage("hello")}}
+	for range 2 {
+		if _, err := recorder.Complete(context.Background(), request); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := NewReplayProvider(cassette)
+
+	for _, want := range []string{"first", "second", "second"} {
+		response, err := replay.Complete(context.Background(), request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Text != want {
+			t.Fatalf("got %q, want %q", response.Text, want)
+		}
+	}
+
+	other := Request{Model: "test-cassette-model", Messages: []Message{UserMessage("goodbye")}}
+	if _, err := replay.Complete(context.Background(), other); !errors.Is(err, ErrCassetteMiss) {
+		t.Fatalf("got %v, want a cassette miss", err)
+	}
+}
@@ -0,0 +1,220 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"unicode"
+)
+
+const (
+	EmbedderLocal  = "local"
+	EmbedderOpenAI = "openai"
+	EmbedderOllama = "ollama"
+)
+
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Name identifies the embedding model, since vectors from different
+	// models cannot be compared.
+	Name() string
+}
+
+// LLM_EMBEDDER selects the backend, defaulting to the one matching
+// LLM_PROVIDER where it has embeddings and to the local embedder otherwise.
+func NewEmbedderFromEnv() (Embedder, error) {
+	name := os.Getenv("LLM_EMBEDDER")
+	if name == "" {
+		switch provider := os.Getenv("LLM_PROVIDER"); provider {
+		case ProviderOpenAI, ProviderOllama:
+			name = provider
+		default:
+			name = EmbedderLocal
+		}
+	}
+
+	switch name {
+	case EmbedderLocal:
+		return NewLocalEmbedder(LocalEmbeddingDimensions), nil
+	case EmbedderOpenAI:
+		return NewOpenAIEmbedder(os.Getenv("OPENAI_BASE_URL"), os.Getenv("OPENAI_API_KEY"), os.Getenv("OPENAI_EMBEDDING_MODEL")), nil
+	case EmbedderOllama:
+		return NewOllamaEmbedder(os.Getenv("OLLAMA_BASE_URL"), os.Getenv("OLLAMA_EMBEDDING_MODEL")), nil
+	default:
+		return nil, fmt.Errorf("unknown llm embedder: %s", name)
+	}
+}
+
+const LocalEmbeddingDimensions = 256
+
+// LocalEmbedder hashes words and their character trigrams into a fixed size
+// vector. It needs no model and always gives the same vector for the same
+// text, which makes it suitable for tests and offline development.
+type LocalEmbedder struct {
+	dimensions int
+}
+
+func NewLocalEmbedder(dimensions int) *LocalEmbedder {
+	return &LocalEmbedder{dimensions}
+}
+
+func (embedder *LocalEmbedder) Name() string {
+	return fmt.Sprintf("local-%d", embedder.dimensions)
+}
+
+func (embedder *LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = embedder.embed(text)
+	}
+
+	return vectors, nil
+}
+
+func (embedder *LocalEmbedder) embed(text string) []float32 {
+	vector := make([]float32, embedder.dimensions)
+
+	add := func(feature string, weight float32) {
+		hash := fnv.New64a()
+		hash.Write([]byte(feature))
+		sum := hash.Sum64()
+
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vector[sum%uint64(embedder.dimensions)] += weight
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		add("w:"+word, 1)
+
+		runes := []rune(word)
+		for i := 0; i+3 <= len(runes); i++ {
+			add("t:"+string(runes[i:i+3]), 0.3)
+		}
+	}
+
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+
+	return vector
+}
+
+type OpenAIEmbedder struct {
+	baseUrl string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func NewOpenAIEmbedder(baseUrl, apiKey, model string) *OpenAIEmbedder {
+	if baseUrl == "" {
+		baseUrl = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+
+	return &OpenAIEmbedder{strings.TrimSuffix(baseUrl, "/"), apiKey, model, &http.Client{}}
+}
+
+func (embedder *OpenAIEmbedder) Name() string {
+	return "openai-" + embedder.model
+}
+
+func (embedder *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body := struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}{embedder.model, texts}
+
+	headers := map[string]string{}
+	if embedder.apiKey != "" {
+		headers["Authorization"] = "Bearer " + embedder.apiKey
+	}
+
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := postJson(ctx, embedder.client, embedder.baseUrl+"/embeddings", headers, body, &response); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, data := range response.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+	for i, vector := range vectors {
+		if vector == nil {
+			return nil, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+
+	return vectors, nil
+}
+
+type OllamaEmbedder struct {
+	baseUrl string
+	model   string
+	client  *http.Client
+}
+
+func NewOllamaEmbedder(baseUrl, model string) *OllamaEmbedder {
+	if baseUrl == "" {
+		baseUrl = "http://localhost:11434"
+	}
+	if model == "" {
+		model = "nomic-embed-text"
+	}
+
+	return &OllamaEmbedder{strings.TrimSuffix(baseUrl, "/"), model, &http.Client{}}
+}
+
+func (embedder *OllamaEmbedder) Name() string {
+	return "ollama-" + embedder.model
+}
+
+func (embedder *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body := struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}{embedder.model, texts}
+
+	var response struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Error      string      `json:"error"`
+	}
+	if err := postJson(ctx, embedder.client, embedder.baseUrl+"/api/embed", nil, body, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != "" {
+		return nil, fmt.Errorf("response error: %s", response.Error)
+	}
+	if len(response.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(response.Embeddings))
+	}
+
+	return response.Embeddings, nil
+}
@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/nvdaz/find-a-friend-api/vector"
+)
+
+func TestLocalEmbedder(t *testing.T) {
+	embedder := NewLocalEmbedder(64)
+	texts := []string{
+		"Loves hiking and rock climbing on weekends",
+		"Enjoys hiking, climbing and camping",
+		"Collects stamps and restores antique clocks",
+		"",
+	}
+
+	vectors, err := embedder.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != len(texts) {
+		t.Fatalf("got %d vectors for %d texts", len(vectors), len(texts))
+	}
+	for i, v := range vectors {
+		if len(v) != 64 {
+			t.Fatalf("vector %d has %d dimensions", i, len(v))
+		}
+	}
+
+	again, _ := embedder.Embed(context.Background(), texts[:1])
+	if !reflect.DeepEqual(again[0], vectors[0]) {
+		t.Error("same text embedded differently")
+	}
+
+	if vector.Cosine(vectors[0], vectors[1]) <= vector.Cosine(vectors[0], vectors[2]) {
+		t.Error("related texts are no closer than unrelated ones")
+	}
+	if got := vector.Cosine(vectors[0], vectors[0]); got < 0.999 {
+		t.Errorf("text is %v similar to itself", got)
+	}
+	if got := vector.Cosine(vectors[3], vectors[0]); got != 0 {
+		t.Errorf("empty text scored %v", got)
+	}
+}
@@ -0,0 +1,335 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const (
+	StrategyFencedJson = "fenced_json"
+	StrategyFenced     = "fenced"
+	StrategyBalanced   = "balanced_object"
+	StrategyTruncated  = "truncated_object"
+)
+
+const maxCandidates = 20
+
+var fencePattern = regexp.MustCompile("(?s)```([A-Za-z]*)[^\\n]*\\n(.*?)```")
+
+type candidate struct {
+	text     string
+	strategy string
+}
+
+// extractCandidates lists the places a JSON object might be hiding in a
+// response, most likely first: fenced json blocks, other fenced blocks and then
+// every object in the order it starts, so an object cut off by truncation is
+// tried before the complete objects nested inside it.
+func extractCandidates(response string) []candidate {
+	var fencedJson, fenced []candidate
+	for _, match := range fencePattern.FindAllStringSubmatch(response, -1) {
+		for _, object := range findObjects(match[2]) {
+			if strings.EqualFold(match[1], "json") {
+				fencedJson = append(fencedJson, candidate{object.text, StrategyFencedJson})
+			} else {
+				fenced = append(fenced, candidate{object.text, StrategyFenced})
+			}
+		}
+	}
+
+	candidates := append(fencedJson, fenced...)
+	candidates = append(candidates, findObjects(response)...)
+
+	seen := map[string]bool{}
+	unique := []candidate{}
+	for _, c := range candidates {
+		if seen[c.text] {
+			continue
+		}
+		seen[c.text] = true
+		unique = append(unique, c)
+		if len(unique) == maxCandidates {
+			break
+		}
+	}
+
+	return unique
+}
+
+// findObjects lists the objects in text in the order they start. Each start
+// costs a scan to the end of the text when it never closes, so the search
+// gives up after maxCandidates of those.
+func findObjects(text string) []candidate {
+	var objects []candidate
+
+	unclosed := 0
+	skipUntil := 0
+	for start := 0; start < len(text) && len(objects) < maxCandidates; start++ {
+		if text[start] != '{' || start < skipUntil {
+			continue
+		}
+
+		end := matchBrace(text, start)
+		if end == -1 {
+			if unclosed == 0 {
+				objects = append(objects, candidate{text[start:], StrategyTruncated})
+			}
+			if unclosed++; unclosed == maxCandidates {
+				break
+			}
+			continue
+		}
+
+		object := text[start : end+1]
+		objects = append(objects, candidate{object, StrategyBalanced})
+		if json.Valid([]byte(object)) || json.Valid([]byte(repairJson(object))) {
+			skipUntil = end + 1
+		}
+	}
+
+	return objects
+}
+
+func matchBrace(text string, start int) int {
+	depth := 0
+	inString := false
+	for i := start; i < len(text); i++ {
+		switch c := text[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// repairJson fixes the syntax errors models commonly make: single quoted
+// strings, trailing commas and output cut off before the closing brackets.
+func repairJson(text string) string {
+	var out strings.Builder
+	var stack []byte
+	inString := false
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		if inString {
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(text) {
+				i++
+				out.WriteByte(text[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+			out.WriteByte(c)
+		case '\'':
+			end := closingQuote(text, i+1)
+			if end == -1 {
+				out.WriteByte(c)
+				continue
+			}
+			quoted, _ := json.Marshal(strings.ReplaceAll(text[i+1:end], `\'`, `'`))
+			out.Write(quoted)
+			i = end
+		case ',':
+			rest := strings.TrimLeft(text[i+1:], " \t\r\n")
+			if rest != "" && (rest[0] == '}' || rest[0] == ']') {
+				continue
+			}
+			out.WriteByte(c)
+		case '{', '[':
+			stack = append(stack, c)
+			out.WriteByte(c)
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	repaired := out.String()
+	if inString {
+		repaired += `"`
+	}
+
+	return closeBrackets(repaired, stack)
+}
+
+func closingQuote(text string, start int) int {
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '\'':
+			return i
+		}
+	}
+
+	return -1
+}
+
+func closeBrackets(text string, stack []byte) string {
+	if len(stack) == 0 {
+		return text
+	}
+
+	var out strings.Builder
+	text = strings.TrimRight(text, " \t\r\n,:")
+	out.Grow(len(text) + len(stack))
+	out.WriteString(text)
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == '{' {
+			out.WriteByte('}')
+		} else {
+			out.WriteByte(']')
+		}
+	}
+
+	return out.String()
+}
+
+// truncations lists the ways a truncated object can be closed into valid
+// JSON, longest first, dropping one trailing partial member at a time. Only
+// maxCandidates cuts are tried, as each one costs a pass over the text.
+func truncations(text string) []string {
+	var valid []string
+	for attempt := 0; attempt < maxCandidates; attempt++ {
+		if repaired := repairJson(text); json.Valid([]byte(repaired)) {
+			valid = append(valid, repaired)
+		}
+
+		cut := lastOpenComma(text)
+		if cut == -1 {
+			break
+		}
+		text = text[:cut]
+	}
+
+	return valid
+}
+
+// lastOpenComma finds the last comma separating members of a container that
+// is still open at the end of text, so cutting there drops the partial member
+// of the innermost such container without splitting a completed one.
+func lastOpenComma(text string) int {
+	var commas []int
+	inString := false
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{' || c == '[':
+			commas = append(commas, -1)
+		case c == '}' || c == ']':
+			if len(commas) > 0 {
+				commas = commas[:len(commas)-1]
+			}
+		case c == ',' && len(commas) > 0:
+			commas[len(commas)-1] = i
+		}
+	}
+
+	for i := len(commas) - 1; i >= 0; i-- {
+		if commas[i] != -1 {
+			return commas[i]
+		}
+	}
+
+	return -1
+}
+
+// decodings returns the ways a candidate can be read as JSON, most faithful
+// first, or the syntax error when there are none.
+func decodings(c candidate) ([]candidate, error) {
+	var value any
+	err := json.Unmarshal([]byte(c.text), &value)
+	if err == nil {
+		return []candidate{c}, nil
+	}
+
+	repaired := []string{repairJson(c.text)}
+	if c.strategy == StrategyTruncated {
+		repaired = truncations(c.text)
+	}
+
+	decoded := []candidate{}
+	for _, text := range repaired {
+		if json.Valid([]byte(text)) {
+			decoded = append(decoded, candidate{text, c.strategy + "_repaired"})
+		}
+	}
+	if len(decoded) == 0 {
+		return nil, err
+	}
+
+	return decoded, nil
+}
+
+var errNoJson = errors.New("no json object found in response")
+
+// unmarshalResponse decodes the first candidate object that both parses and
+// matches the schema, returning the strategy that found it. When no candidate
+// matches, the first schema violation is preferred over syntax errors so the
+// caller can send a corrective prompt.
+func unmarshalResponse(result any, schema *Schema, response string) (string, error) {
+	var validationErr, syntaxErr error
+
+	for _, c := range extractCandidates(response) {
+		decoded, err := decodings(c)
+		if err != nil {
+			if syntaxErr == nil {
+				syntaxErr = err
+			}
+			continue
+		}
+
+		for _, d := range decoded {
+			var value any
+			if err := json.Unmarshal([]byte(d.text), &value); err != nil {
+				continue
+			}
+
+			if problems := schema.Validate(value); len(problems) > 0 {
+				if validationErr == nil {
+					validationErr = &ValidationError{problems}
+				}
+				continue
+			}
+
+			return d.strategy, json.Unmarshal([]byte(d.text), result)
+		}
+	}
+
+	if validationErr != nil {
+		return "", validationErr
+	}
+	if syntaxErr != nil {
+		return "", syntaxErr
+	}
+
+	return "", errNoJson
+}
@@ -0,0 +1,213 @@
+package llm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testGoal struct {
+	Goal       string  `json:"goal"`
+	Importance float64 `json:"importance"`
+}
+
+type testGoals struct {
+	Goals []testGoal `json:"goals"`
+}
+
+type testValues struct {
+	Analysis   string `json:"analysis"`
+	CoreValues []struct {
+		Value      string  `json:"value"`
+		Importance float64 `json:"importance"`
+	} `json:"core_values"`
+}
+
+// Responses below are taken from failures seen in the llm_calls audit log,
+// with names and details changed.
+var extractCases = []struct {
+	name     string
+	response string
+	strategy string
+	goals    []testGoal
+	err      string
+}{
+	{
+		name:     "bare object",
+		response: `{"goals": [{"goal": "run a marathon", "importance": 0.8}]}`,
+		strategy: StrategyBalanced,
+		goals:    []testGoal{{"run a marathon", 0.8}},
+	},
+	{
+		name: "prose around fenced json",
+		response: "Sure! Based on the conversation, here are the goals:\n\n```json\n" +
+			`{"goals": [{"goal": "learn to cook", "importance": 0.6}]}` +
+			"\n```\n\nLet me know if you need anything else.",
+		strategy: StrategyFencedJson,
+		goals:    []testGoal{{"learn to cook", 0.6}},
+	},
+	{
+		name: "analysis with braces before the object",
+		response: "The user mentions {work} and {family} a lot, so goals center on those.\n" +
+			`{"goals": [{"goal": "get promoted", "importance": 0.9}]}`,
+		strategy: StrategyBalanced,
+		goals:    []testGoal{{"get promoted", 0.9}},
+	},
+	{
+		name: "fenced json preferred over an earlier example block",
+		response: "An example of the format:\n```\n{\"goals\": []}\n```\nThe answer:\n```json\n" +
+			`{"goals": [{"goal": "travel to japan", "importance": 0.7}]}` + "\n```",
+		strategy: StrategyFencedJson,
+		goals:    []testGoal{{"travel to japan", 0.7}},
+	},
+	{
+		name:     "untagged fence",
+		response: "```\n" + `{"goals": [{"goal": "read more", "importance": 0.4}]}` + "\n```",
+		strategy: StrategyFenced,
+		goals:    []testGoal{{"read more", 0.4}},
+	},
+	{
+		name:     "trailing commas",
+		response: `{"goals": [{"goal": "save money", "importance": 0.5,}, {"goal": "move abroad", "importance": 0.3},],}`,
+		strategy: StrategyBalanced + "_repaired",
+		goals:    []testGoal{{"save money", 0.5}, {"move abroad", 0.3}},
+	},
+	{
+		name:     "single quotes",
+		response: `{'goals': [{'goal': 'finish my thesis', 'importance': 1}]}`,
+		strategy: StrategyBalanced + "_repaired",
+		goals:    []testGoal{{"finish my thesis", 1}},
+	},
+	{
+		name:     "single quotes with escaped apostrophe",
+		response: `{'goals': [{'goal': 'visit mom\'s hometown', 'importance': 0.6}]}`,
+		strategy: StrategyBalanced + "_repaired",
+		goals:    []testGoal{{"visit mom's hometown", 0.6}},
+	},
+	{
+		name:     "truncated inside a key",
+		response: `{"goals": [{"goal": "a", "importance": 0.5}, {"goal": "b", "imp`,
+		strategy: StrategyTruncated + "_repaired",
+		goals:    []testGoal{{"a", 0.5}},
+	},
+	{
+		name:     "truncated inside a string",
+		response: `{"goals": [{"goal": "a", "importance": 0.5}, {"goal": "b", "importance": 0.25}, {"goal": "learn the pia`,
+		strategy: StrategyTruncated + "_repaired",
+		goals:    []testGoal{{"a", 0.5}, {"b", 0.25}},
+	},
+	{
+		name:     "truncated after a complete item",
+		response: "```json\n" + `{"goals": [{"goal": "a", "importance": 0.5},`,
+		strategy: StrategyTruncated + "_repaired",
+		goals:    []testGoal{{"a", 0.5}},
+	},
+	{
+		name:     "wrong shape reports schema problems",
+		response: `{"goal": "a", "importance": 0.5}`,
+		err:      `missing required key "goals"`,
+	},
+	{
+		name:     "no json",
+		response: "I'm sorry, I can't help with that.",
+		err:      errNoJson.Error(),
+	},
+}
+
+func TestUnmarshalResponse(t *testing.T) {
+	schema := SchemaFor(testGoals{})
+
+	for _, tc := range extractCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var result testGoals
+			strategy, err := unmarshalResponse(&result, schema, tc.response)
+
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got error %v, want one containing %q", err, tc.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strategy != tc.strategy {
+				t.Errorf("got strategy %q, want %q", strategy, tc.strategy)
+			}
+			if !reflect.DeepEqual(result.Goals, tc.goals) {
+				t.Errorf("got goals %+v, want %+v", result.Goals, tc.goals)
+			}
+		})
+	}
+}
+
+func TestUnmarshalResponseTruncatedReportsOuterObject(t *testing.T) {
+	var result struct {
+		Goals []struct {
+			Goal       string  `json:"goal"`
+			Importance float64 `json:"importance" jsonschema:"minimum=1"`
+		} `json:"goals"`
+	}
+
+	_, err := unmarshalResponse(&result, SchemaFor(result), `{"goals": [{"goal": "a", "importance": 0.5}, {"goal": "b", "imp`)
+	if err == nil {
+		t.Fatal("expected a validation error")
+	}
+	if strings.Contains(err.Error(), `missing required key "goals"`) {
+		t.Fatalf("error describes the nested object instead of the response: %v", err)
+	}
+	if !strings.Contains(err.Error(), "$.goals[0].importance") {
+		t.Fatalf("got %v, want a problem with $.goals[0].importance", err)
+	}
+}
+
+func TestUnmarshalResponseAnalysisFirst(t *testing.T) {
+	response := "```json\n" + `{"analysis": "they value {honesty}, and growth", "core_values": [{"value": "honesty", "importance": 0.9}, {"value": "growth", "importance": 0.7},]}` + "\n```"
+
+	var result testValues
+	strategy, err := unmarshalResponse(&result, SchemaFor(testValues{}), response)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strategy != StrategyFencedJson+"_repaired" {
+		t.Errorf("got strategy %q", strategy)
+	}
+	if len(result.CoreValues) != 2 || result.Analysis != "they value {honesty}, and growth" {
+		t.Errorf("got %+v", result)
+	}
+}
+
+func TestTruncations(t *testing.T) {
+	got := truncations(`{"tags": [{"tag": "hiker", "emoji": "🥾"}, {"tag": "coo`)
+	want := []string{
+		`{"tags": [{"tag": "hiker", "emoji": "🥾"}, {"tag": "coo"}]}`,
+		`{"tags": [{"tag": "hiker", "emoji": "🥾"}]}`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func FuzzUnmarshalResponse(f *testing.F) {
+	for _, tc := range extractCases {
+		f.Add(tc.response)
+	}
+	f.Add(`{"goals": [{"goal": "a\"}, {", "importance": 0.5}`)
+	f.Add("```json\n{'goals': [")
+	f.Add(`{{{{[[[[`)
+	// Used to take minutes, with a repair pass per comma.
+	f.Add(strings.Repeat(`{"a":`, 5000) + strings.Repeat("x,", 5000))
+
+	schema := SchemaFor(testGoals{})
+	f.Fuzz(func(t *testing.T, response string) {
+		var result testGoals
+		strategy, err := unmarshalResponse(&result, schema, response)
+		if err == nil && strategy == "" {
+			t.Fatalf("decoded %q without reporting a strategy", response)
+		}
+		if err != nil && strategy != "" {
+			t.Fatalf("reported strategy %q with error %v", strategy, err)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+func postJson(ctx context.Context, client *http.Client, url string, headers map[string]string, body any, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, respData)
+	}
+
+	if err := json.Unmarshal(respData, result); err != nil {
+		return fmt.Errorf("unmarshal json: %w", err)
+	}
+
+	return nil
+}
+
+func mapModel(models map[Model]string, model Model) string {
+	if name, ok := models[model]; ok {
+		return name
+	}
+
+	return model.String()
+}
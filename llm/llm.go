@@ -1,13 +1,12 @@
 package llm
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
 	"strings"
-
-	"github.com/gorilla/websocket"
+	"time"
 )
 
 const (
@@ -23,97 +22,202 @@ func (model Model) String() string {
 	return string(model)
 }
 
-func unmarshalResponse(result any, response string) error {
-	start := strings.Index(response, "{")
-	if start == -1 {
-		return fmt.Errorf("no opening brace found in response")
-	}
+type Chain []Model
 
-	end := strings.LastIndex(response, "}")
-	if end == -1 {
-		return fmt.Errorf("no closing brace found in response")
-	}
+const AttemptTimeout = 45 * time.Second
+
+var ErrChainUnavailable = errors.New("every model in the chain is unavailable")
 
-	jsonString := response[start : end+1]
-	return json.Unmarshal([]byte(jsonString), &result)
+type ValidationError struct {
+	Errors []string
 }
 
-type promptData struct {
-	Action      string   `json:"action"`
-	Model       string   `json:"model"`
-	Prompt      string   `json:"prompt"`
-	System      string   `json:"system"`
-	Temperature *float64 `json:"temperature"`
+func (err *ValidationError) Error() string {
+	return "response does not match schema: " + strings.Join(err.Errors, "; ")
 }
 
-func GetResponse(model Model, prompt, system string, temperature *float64) (*string, error) {
-	uri := os.Getenv("LLM_WEBSOCKET_URI")
+func correctionMessage(problems []string) Message {
+	return UserMessage(fmt.Sprintf("That answer did not match the required JSON Schema:\n- %s\n\nAnswer again, making sure the JSON object fixes every problem listed above.", strings.Join(problems, "\n- ")))
+}
 
-	conn, _, err := websocket.DefaultDialer.Dial(uri, nil)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Close()
-
-	requestData := promptData{
-		Action:      "runModel",
-		Model:       model.String(),
-		Prompt:      prompt,
-		System:      system,
-		Temperature: temperature,
-	}
-	message, err := json.Marshal(requestData)
-	if err != nil {
-		return nil, err
+// withSystem adds a system message after any leading system messages, so it
+// stays ahead of the conversation for providers that require that.
+func withSystem(messages []Message, content string) []Message {
+	i := 0
+	for i < len(messages) && messages[i].Role == RoleSystem {
+		i++
 	}
 
-	err = conn.WriteMessage(websocket.TextMessage, message)
-	if err != nil {
-		return nil, err
+	result := make([]Message, 0, len(messages)+1)
+	result = append(result, messages[:i]...)
+	result = append(result, SystemMessage(content))
+	return append(result, messages[i:]...)
+}
+
+type Client struct {
+	provider Provider
+	embedder Embedder
+	calls    CallStore
+}
+
+func NewClient(provider Provider, embedder Embedder, calls CallStore) *Client {
+	return &Client{provider, embedder, calls}
+}
+
+func (client *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
 	}
 
-	for {
-		_, message, err = conn.ReadMessage()
-		if err != nil {
-			return nil, fmt.Errorf("read message: %w", err)
+	ctx, cancel := context.WithTimeout(ctx, AttemptTimeout)
+	defer cancel()
+
+	return client.embedder.Embed(ctx, texts)
+}
+
+func (client *Client) EmbeddingModel() string {
+	return client.embedder.Name()
+}
+
+func promptMessages(prompt, system string) []Message {
+	return []Message{SystemMessage(system), UserMessage(prompt)}
+}
+
+func (client *Client) GetResponse(ctx context.Context, chain Chain, prompt, system string, temperature *float64) (*string, error) {
+	return client.Chat(ctx, chain, promptMessages(prompt, system), Options{Temperature: temperature})
+}
+
+func (client *Client) GetResponseJson(ctx context.Context, result any, chain Chain, prompt, system string, temperature *float64) error {
+	return client.ChatJson(ctx, result, chain, promptMessages(prompt, system), Options{Temperature: temperature})
+}
+
+// Chat sends the conversation to the first available model in the chain; pass
+// a one-model chain to pin a model.
+func (client *Client) Chat(ctx context.Context, chain Chain, messages []Message, options Options) (*string, error) {
+	return client.chat(ctx, chain, messages, options, 0, 0, nil)
+}
+
+// chat tries each model in the chain in turn. When parse is set, the response
+// is passed through it so the audit log records the parse outcome, and it is
+// only cached once it parses. Step and retry are recorded with the call: the
+// tool round trip it belongs to and how many replies were sent back for
+// correction before it.
+func (client *Client) chat(ctx context.Context, chain Chain, messages []Message, options Options, step, retry int, parse func(string) (string, error)) (*string, error) {
+	err := ErrChainUnavailable
+
+	for _, model := range chain {
+		breaker := circuitBreakers.get(model)
+		if !breaker.allow() {
+			continue
 		}
 
-		fmt.Println(string(message))
-		var response struct {
-			Result string `json:"result"`
-			Error  string `json:"error"`
+		request := Request{Model: model, Messages: messages, Options: options}
+		record := CallRecord{Model: model, System: request.System(), Prompt: request.Transcript(), Step: step, Retry: retry}
+
+		attemptCtx, pending := withPendingCache(ctx)
+		if parse == nil {
+			attemptCtx = ctx
 		}
-		if err := json.Unmarshal(message, &response); err != nil {
-			return nil, fmt.Errorf("unmarshal json: %w", err)
+
+		start := time.Now()
+		var response *Response
+		response, err = client.complete(attemptCtx, request)
+		record.Latency = time.Since(start)
+
+		if err == nil {
+			breaker.success()
+
+			if parse != nil {
+				record.Strategy, err = parse(response.Text)
+				pending.settle(err == nil)
+			}
+
+			record.Response = &response.Text
+			record.Outcome = outcomeOf(err)
+			if err != nil {
+				message := err.Error()
+				record.Error = &message
+			}
+			client.recordCall(ctx, record)
+
+			return &response.Text, err
 		}
 
-		if response.Result != "" {
-			return &response.Result, nil
+		message := err.Error()
+		record.Outcome = OutcomeRequestError
+		record.Error = &message
+		client.recordCall(ctx, record)
+
+		if ctx.Err() != nil || errors.Is(err, ErrBudgetExceeded) {
+			breaker.release()
+			return nil, err
 		}
-		if response.Error != "" {
-			return nil, fmt.Errorf("response error: %s", response.Error)
+
+		if errors.Is(err, ErrRateLimited) {
+			breaker.release()
+			log.Println("Model", model, "saturated, falling back")
+			continue
+		}
+
+		if errors.Is(err, ErrUnpricedModel) {
+			breaker.release()
+			log.Println("Model", model, "has no price, falling back")
+			continue
 		}
+
+		breaker.failure()
+		log.Println("Model", model, "failed, falling back:", err)
 	}
+
+	return nil, err
 }
 
-func GetResponseJson(result any, model Model, prompt, system string, temperature *float64) error {
+func (client *Client) complete(ctx context.Context, request Request) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, AttemptTimeout)
+	defer cancel()
+
+	return client.provider.Complete(ctx, request)
+}
+
+// ChatJson decodes the reply into result, validating it against a schema
+// derived from result's type. An invalid reply is answered with a correction
+// turn listing the problems and the model is asked again.
+func (client *Client) ChatJson(ctx context.Context, result any, chain Chain, messages []Message, options Options) error {
 	retries := 3
 
+	schema := SchemaFor(result)
+	messages = withSystem(messages, fmt.Sprintf("The JSON object must conform to this JSON Schema:\n%s", schema))
+	attemptMessages := messages
+
+	parse := func(response string) (string, error) {
+		return unmarshalResponse(result, schema, response)
+	}
+
 	var err error
 
 	for i := 0; i < retries; i++ {
-		log.Println(system)
-		response, err := GetResponse(model, prompt, system, temperature)
-		if err != nil {
-			continue
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		attemptCtx := ctx
+		if i > 0 {
+			attemptCtx = WithoutCache(ctx)
 		}
-		log.Println(*response, err)
 
-		err = unmarshalResponse(result, *response)
+		var response *string
+		response, err = client.chat(attemptCtx, chain, attemptMessages, options, 0, i, parse)
 		if err == nil {
-			fmt.Println(result)
 			return nil
 		}
+		if errors.Is(err, ErrBudgetExceeded) {
+			return err
+		}
+
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			attemptMessages = append(messages[:len(messages):len(messages)], AssistantMessage(*response), correctionMessage(validationErr.Errors))
+		}
 	}
 
 	return err
@@ -0,0 +1,41 @@
+// Package llmtest serves recorded model responses so code that calls the
+// model can be tested offline.
+package llmtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nvdaz/find-a-friend-api/llm"
+)
+
+// Client replays the cassette at path and fails the test on any request that
+// was not recorded. With LLM_CASSETTE_MODE=record it records a fresh cassette
+// through the provider configured in the environment instead.
+func Client(t testing.TB, path string) *llm.Client {
+	t.Helper()
+
+	if os.Getenv("LLM_CASSETTE_MODE") == llm.CassetteRecord {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+
+		t.Setenv("LLM_CASSETTE_PATH", path)
+		provider, err := llm.NewProviderFromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return llm.NewClient(provider, nil, nil)
+	}
+
+	cassette, err := llm.LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return llm.NewClient(llm.NewReplayProvider(cassette), nil, nil)
+}
@@ -1,32 +1,48 @@
 package match
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 
 	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/llm/prompt"
 	"github.com/nvdaz/find-a-friend-api/model"
 )
 
-const CandidateMatchesCount = 4
+const (
+	CandidateMatchesCount = 4
+	CandidateToolSteps    = 12
+)
+
+// A Shortlisted user is a candidate match and why they were picked.
+type Shortlisted struct {
+	Id     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+func GenerateCandidateMatches(ctx context.Context, client *llm.Client, user model.User, users []model.User) ([]Shortlisted, error) {
+	ctx = llm.WithFeature(ctx, "GenerateCandidateMatches")
 
-func GenerateCandidateMatches(user model.User, users []model.User) ([]string, error) {
-	type UserSummary struct {
-		Id      string `json:"id"`
-		Summary string `json:"summary"`
+	type Candidate struct {
+		Id       string `json:"id"`
+		Name     string `json:"name"`
+		Subtitle string `json:"subtitle"`
 	}
 
-	var userSummaries []UserSummary
+	var candidates []Candidate
 	for _, user := range users {
-		userSummaries = append(userSummaries, UserSummary{Id: user.Id, Summary: user.Profile.Summary})
+		if user.Profile == nil {
+			continue
+		}
+		candidates = append(candidates, Candidate{Id: user.Id, Name: user.Name, Subtitle: user.Profile.Subtitle})
 	}
 
 	d := struct {
-		User  model.User    `json:"user"`
-		Users []UserSummary `json:"users"`
+		User       Candidate   `json:"user"`
+		Candidates []Candidate `json:"candidates"`
 	}{
-		User:  user,
-		Users: userSummaries,
+		User:       Candidate{Id: user.Id, Name: user.Name, Subtitle: user.Profile.Subtitle},
+		Candidates: candidates,
 	}
 
 	data, err := json.Marshal(d)
@@ -35,10 +51,17 @@ func GenerateCandidateMatches(user model.User, users []model.User) ([]string, er
 	}
 
 	matches := struct {
-		Matches []string `json:"matches"`
+		Matches []Shortlisted `json:"matches"`
 	}{}
 
-	err = llm.GetResponseJson(&matches, llm.ModelClaudeSonnet, string(data), fmt.Sprintf("Your job is to generate a list of %d potential matches based on the user summaries provided. Respond with JSON with a key 'matches', a list of user IDs that are potential matches.", CandidateMatchesCount), nil)
+	system, err := prompt.CandidateMatches.Render(ctx, prompt.Count{Count: CandidateMatchesCount})
+	if err != nil {
+		return nil, err
+	}
+
+	tools := matchTools(append([]model.User{user}, users...))
+	messages := []llm.Message{llm.SystemMessage(system), llm.UserMessage(string(data))}
+	err = client.RunTools(ctx, &matches, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, messages, tools, CandidateToolSteps, llm.Options{})
 	if err != nil {
 		return nil, err
 	}
@@ -1,14 +1,18 @@
 package match
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 
+	"github.com/nvdaz/find-a-friend-api/compatibility"
 	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/llm/prompt"
 	"github.com/nvdaz/find-a-friend-api/model"
 )
 
-func ExplainMatch(user1, user2 model.User) (string, error) {
+func ExplainMatch(ctx context.Context, client *llm.Client, user1, user2 model.User) (string, error) {
+	ctx = llm.WithFeature(ctx, "ExplainMatch")
+
 	data := struct {
 		User1 model.User `json:"user1"`
 		User2 model.User `json:"user2"`
@@ -17,7 +21,7 @@ func ExplainMatch(user1, user2 model.User) (string, error) {
 		User2: user2,
 	}
 
-	prompt, err := json.Marshal(data)
+	input, err := json.Marshal(data)
 	if err != nil {
 		return "", err
 	}
@@ -26,48 +30,87 @@ func ExplainMatch(user1, user2 model.User) (string, error) {
 		Explanation string `json:"explanation"`
 	}{}
 
-	err = llm.GetResponseJson(&explanation, llm.ModelClaudeSonnet, string(prompt), "Your job is to explain why these two users are a good match. Go into as much detail as possible with a 200 word justifications. Respond with a JSON object without formatting containing a single key 'explanation', which is a string that explains why these two users are a good match.", nil)
+	system, err := prompt.ExplainMatch.Render(ctx, prompt.None{})
+	if err != nil {
+		return "", err
+	}
+
+	err = client.GetResponseJson(ctx, &explanation, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, string(input), system, nil)
 
 	return explanation.Explanation, err
 }
 
-func DecideBestMatch(explanations map[string]string) (string, error) {
-	prompt, err := json.Marshal(explanations)
+// DecideBestMatch returns the ID of the best match, the IDs of any matches
+// the model finds just as good and the reasoning behind the choice.
+func DecideBestMatch(ctx context.Context, client *llm.Client, explanations map[string]string) (string, []string, string, error) {
+	ctx = llm.WithFeature(ctx, "DecideBestMatch")
+
+	input, err := json.Marshal(explanations)
 	if err != nil {
-		return "", err
+		return "", nil, "", err
 	}
 
 	bestMatch := struct {
-		BestMatch string `json:"best_match"`
+		Reasoning string   `json:"reasoning"`
+		BestMatch string   `json:"best_match"`
+		TiedWith  []string `json:"tied_with"`
 	}{}
 
-	err = llm.GetResponseJson(&bestMatch, llm.ModelGpt4, string(prompt), "Your job is to decide which of the potential matches is the best match based on the explanations provided. Respond with a JSON object without formatting containing a single key 'best_match', which is the ID of the best match.", nil)
+	system, err := prompt.DecideBestMatch.Render(ctx, prompt.None{})
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	err = client.GetResponseJson(ctx, &bestMatch, llm.Chain{llm.ModelGpt4, llm.ModelClaudeSonnet, llm.ModelGpt3p5}, string(input), system, nil)
 
-	return bestMatch.BestMatch, err
+	return bestMatch.BestMatch, bestMatch.TiedWith, bestMatch.Reasoning, err
 }
 
-func ExplainMatchToUser(user1, user2 model.User) (string, error) {
+// ExplainMatchToUser writes the match card user1 sees about user2.
+func ExplainMatchToUser(ctx context.Context, client *llm.Client, user1, user2 model.User) (model.MatchExplanation, error) {
+	ctx = llm.WithFeature(ctx, "ExplainMatchToUser")
+
+	shared := compatibility.SharedInterests(user1.Profile, user2.Profile)
+	sharedNames := make([]string, len(shared))
+	for i, interest := range shared {
+		sharedNames[i] = interest.Interest
+	}
+
 	data := struct {
-		User1 model.User `json:"user1"`
-		User2 model.User `json:"user2"`
+		User1           model.User `json:"user1"`
+		User2           model.User `json:"user2"`
+		SharedInterests []string   `json:"shared_interests"`
 	}{
-		User1: user1,
-		User2: user2,
+		User1:           user1,
+		User2:           user2,
+		SharedInterests: sharedNames,
 	}
 
-	prompt, err := json.Marshal(data)
+	input, err := json.Marshal(data)
 	if err != nil {
-		return "", err
+		return model.MatchExplanation{}, err
 	}
 
-	explanation := struct {
-		Explanation string `json:"explanation"`
+	card := struct {
+		Reason               string   `json:"reason"`
+		ComplementaryTraits  []string `json:"complementary_traits" jsonschema:"maxItems=3"`
+		ConversationStarters []string `json:"conversation_starters" jsonschema:"minItems=3,maxItems=3"`
 	}{}
 
-	err = llm.GetResponseJson(&explanation, llm.ModelGpt3p5, string(prompt), fmt.Sprintf("You are a matchmaker. Write a personalized message to %q (refer to them as 'you') why %q would be a good friend for them. Go into as much detail as possible with a 1-paragraph, 60 word justification. Be sure to use the matched user's name and specific details about their profile in your explanation. Use casual, friendly language. Respond with a JSON object without formatting containing a single key 'explanation'.", user1.Name, user2.Name), nil)
+	system, err := prompt.ExplainMatchToUser.Render(ctx, prompt.Pair{UserName: user1.Name, OtherName: user2.Name})
 	if err != nil {
-		return "", err
+		return model.MatchExplanation{}, err
+	}
+
+	err = client.GetResponseJson(ctx, &card, llm.Chain{llm.ModelGpt3p5, llm.ModelClaudeHaiku}, string(input), system, nil)
+	if err != nil {
+		return model.MatchExplanation{}, err
 	}
 
-	return explanation.Explanation, nil
+	return model.MatchExplanation{
+		Reason:               card.Reason,
+		SharedInterests:      shared,
+		ComplementaryTraits:  card.ComplementaryTraits,
+		ConversationStarters: card.ConversationStarters,
+	}, nil
 }
@@ -0,0 +1,98 @@
+package match
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/llm/prompt"
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+type groupMember struct {
+	Name      string   `json:"name"`
+	Summary   string   `json:"summary"`
+	Interests []string `json:"interests"`
+	Topics    []string `json:"topics"`
+	Hobbies   []string `json:"hobbies"`
+}
+
+func newGroupMember(user model.User) groupMember {
+	member := groupMember{Name: user.Name, Summary: user.Profile.Summary, Hobbies: user.Profile.Hobbies}
+	for _, interest := range user.Profile.Interests {
+		member.Interests = append(member.Interests, interest.Interest)
+	}
+	for _, topic := range user.Profile.Topics {
+		member.Topics = append(member.Topics, topic.Topic)
+	}
+
+	return member
+}
+
+func IntroduceGroup(ctx context.Context, client *llm.Client, members []model.User, themes []string) (string, string, error) {
+	ctx = llm.WithFeature(ctx, "IntroduceGroup")
+
+	data := struct {
+		Members []groupMember `json:"members"`
+		Themes  []string      `json:"themes"`
+	}{Themes: themes}
+	for _, member := range members {
+		data.Members = append(data.Members, newGroupMember(member))
+	}
+
+	input, err := json.Marshal(data)
+	if err != nil {
+		return "", "", err
+	}
+
+	introduction := struct {
+		Name  string `json:"name"`
+		Intro string `json:"intro"`
+	}{}
+
+	system, err := prompt.IntroduceGroup.Render(ctx, prompt.Count{Count: len(members)})
+	if err != nil {
+		return "", "", err
+	}
+
+	err = client.GetResponseJson(ctx, &introduction, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, string(input), system, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	return introduction.Name, introduction.Intro, nil
+}
+
+func ExplainGroupToUser(ctx context.Context, client *llm.Client, user model.User, others []model.User, themes []string) (string, error) {
+	ctx = llm.WithFeature(ctx, "ExplainGroupToUser")
+
+	data := struct {
+		User   groupMember   `json:"user"`
+		Others []groupMember `json:"others"`
+		Themes []string      `json:"themes"`
+	}{User: newGroupMember(user), Themes: themes}
+	for _, other := range others {
+		data.Others = append(data.Others, newGroupMember(other))
+	}
+
+	input, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	explanation := struct {
+		Explanation string `json:"explanation"`
+	}{}
+
+	system, err := prompt.ExplainGroupToUser.Render(ctx, prompt.Member{UserName: user.Name})
+	if err != nil {
+		return "", err
+	}
+
+	err = client.GetResponseJson(ctx, &explanation, llm.Chain{llm.ModelGpt3p5, llm.ModelClaudeHaiku}, string(input), system, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return explanation.Explanation, nil
+}
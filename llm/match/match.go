@@ -3,30 +3,74 @@ package match
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/nvdaz/find-a-friend-api/compatibility"
+	"github.com/nvdaz/find-a-friend-api/llm"
 	"github.com/nvdaz/find-a-friend-api/model"
 	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/semaphore"
 )
 
-func GenerateMatch(user model.User, users []model.User) (*string, error) {
-	candidates, err := GenerateCandidateMatches(user, users)
+// GenerateMatch picks the best match for user. Weights are the user's
+// compatibility weights, learned from how their past matches went. The
+// returned run records every user considered and how the choice was made, and
+// is returned as far as it got when an error stops it.
+func GenerateMatch(ctx context.Context, client *llm.Client, retrieval Retrieval, user model.User, users []model.User, weights compatibility.Weights) (model.MatchRun, error) {
+	run := model.MatchRun{
+		UserId:    user.Id,
+		PoolSize:  len(users),
+		Weights:   model.FeatureWeights(weights),
+		TimingsMs: map[string]int64{},
+	}
+	timed := func(step string, start time.Time) {
+		run.TimingsMs[step] = time.Since(start).Milliseconds()
+	}
+
+	start := time.Now()
+	users = retrieval.Rank(user, users)
+	users = compatibility.PrefilterWeighted(user, users, CandidateMatchesCount, weights)
+	timed("ranking", start)
+
+	considered := map[string]*model.MatchRunCandidate{}
+	run.Candidates = make([]model.MatchRunCandidate, 0, len(users))
+	for _, u := range users {
+		candidate := model.MatchRunCandidate{UserId: u.Id, Name: u.Name}
+		if u.Profile != nil {
+			candidate.Compatibility = compatibility.ScoreWeighted(user.Profile, u.Profile, weights)
+		}
+		run.Candidates = append(run.Candidates, candidate)
+	}
+	for i := range run.Candidates {
+		considered[run.Candidates[i].UserId] = &run.Candidates[i]
+	}
+
+	start = time.Now()
+	candidates, err := GenerateCandidateMatches(ctx, client, user, users)
 	if err != nil {
-		return nil, err
+		return run, err
 	}
+	timed("candidates", start)
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-	group, _ := errgroup.WithContext(ctx)
+	for _, candidate := range candidates {
+		if c, ok := considered[candidate.Id]; ok {
+			c.Shortlisted = true
+			c.ShortlistReason = candidate.Reason
+		}
+	}
 
-	sem := semaphore.NewWeighted(4)
+	start = time.Now()
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	group, groupCtx := errgroup.WithContext(ctx)
 	defer cancel()
 
+	var explanationsMu sync.Mutex
 	explanations := make(map[string]string)
-	for _, candidateId := range candidates {
+	for _, candidate := range candidates {
 		var candidateUser *model.User
 		for _, u := range users {
-			if u.Id == candidateId {
+			if u.Id == candidate.Id {
 				candidateUser = &u
 				break
 			}
@@ -37,41 +81,75 @@ func GenerateMatch(user model.User, users []model.User) (*string, error) {
 		}
 
 		group.Go(func() error {
-			if err = sem.Acquire(ctx, 1); err != nil {
-				return err
-			}
-			defer sem.Release(1)
-
-			explanation, err := ExplainMatch(user, *candidateUser)
+			explanation, err := ExplainMatch(groupCtx, client, user, *candidateUser)
 			if err != nil {
 				return err
 			}
 
-			explanations[candidateId] = explanation
+			explanationsMu.Lock()
+			explanations[candidate.Id] = explanation
+			explanationsMu.Unlock()
 			return nil
 		})
 	}
 
 	if err := group.Wait(); err != nil {
-		return nil, err
+		return run, err
 	}
+	timed("explanations", start)
 
-	bestMatchId, err := DecideBestMatch(explanations)
+	for id, explanation := range explanations {
+		considered[id].Explanation = explanation
+	}
+
+	start = time.Now()
+	bestMatchId, tiedIds, reasoning, err := DecideBestMatch(ctx, client, explanations)
 	if err != nil {
-		return nil, err
+		return run, err
 	}
+	timed("decision", start)
+	run.Reasoning = reasoning
 
-	var bestUser *model.User
+	bestUser := breakTie(user, users, append([]string{bestMatchId}, tiedIds...), weights)
+
+	// Fall back to the best compatibility score when the model names a user
+	// that was not a candidate.
+	if bestUser == nil {
+		var explained []model.User
+		for _, u := range users {
+			if _, ok := explanations[u.Id]; ok {
+				explained = append(explained, u)
+			}
+		}
 
+		ranked := compatibility.RankWeighted(user, explained, weights)
+		if len(ranked) == 0 {
+			return run, fmt.Errorf("got invalid id")
+		}
+		bestUser = &ranked[0].User
+		run.Fallback = true
+	}
+
+	run.ChosenId = bestUser.Id
+	return run, nil
+}
+
+// breakTie returns the user named by ids with the best compatibility score,
+// or nil if none of them is in users.
+func breakTie(user model.User, users []model.User, ids []string, weights compatibility.Weights) *model.User {
+	var tied []model.User
 	for _, u := range users {
-		if u.Id == bestMatchId {
-			bestUser = &u
+		if slices.Contains(ids, u.Id) {
+			tied = append(tied, u)
 		}
 	}
 
-	if bestUser == nil {
-		return nil, fmt.Errorf("got invalid id")
+	switch ranked := compatibility.RankWeighted(user, tied, weights); {
+	case len(ranked) > 0:
+		return &ranked[0].User
+	case len(tied) > 0:
+		return &tied[0]
 	}
 
-	return &bestUser.Id, nil
+	return nil
 }
@@ -0,0 +1,89 @@
+package match
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nvdaz/find-a-friend-api/compatibility"
+	"github.com/nvdaz/find-a-friend-api/llm/llmtest"
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+func testUser(id, name, subtitle string, interests ...string) model.User {
+	profile := &model.InternalProfile{Subtitle: subtitle}
+	profile.Demographics.SpokenLanguages = []string{"English"}
+	profile.Personality = model.Personality{Openness: 4, Conscientiousness: 3, Extroversion: 3, Agreeableness: 4, Neuroticism: 2}
+	for _, interest := range interests {
+		profile.Interests = append(profile.Interests, model.Interest{Interest: interest, Level: 0.8})
+	}
+
+	return model.User{Id: id, Name: name, Profile: profile}
+}
+
+var (
+	testAnn   = testUser("user-ann", "Ann", "Dice roller, trail wanderer", "board games", "hiking")
+	testOther = []model.User{
+		testUser("user-bo", "Bo", "Strategy gamer", "board games", "strategy games"),
+		testUser("user-cy", "Cy", "Weekend hiker", "hiking trails", "camping"),
+		testUser("user-di", "Di", "Jazz pianist", "jazz", "piano"),
+	}
+)
+
+func TestGenerateMatch(t *testing.T) {
+	client := llmtest.Client(t, "testdata/generate_match.json")
+
+	run, err := GenerateMatch(context.Background(), client, NewRetrieval(nil, 0), testAnn, testOther, compatibility.DefaultWeights)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if run.ChosenId != "user-bo" || run.Fallback {
+		t.Errorf("got chosen %q (fallback %v), want user-bo", run.ChosenId, run.Fallback)
+	}
+	if run.Reasoning == "" {
+		t.Error("missing decision reasoning")
+	}
+	if run.PoolSize != len(testOther) || len(run.Candidates) != len(testOther) {
+		t.Fatalf("got pool %d with %d candidates", run.PoolSize, len(run.Candidates))
+	}
+	for _, candidate := range run.Candidates {
+		if !candidate.Shortlisted || candidate.ShortlistReason == "" || candidate.Explanation == "" {
+			t.Errorf("candidate %s missing provenance: %+v", candidate.UserId, candidate)
+		}
+	}
+	if run.Candidates[0].UserId != "user-bo" {
+		t.Errorf("got candidates ranked %+v, want user-bo first", run.Candidates)
+	}
+}
+
+func TestExplainMatchToUser(t *testing.T) {
+	client := llmtest.Client(t, "testdata/explain_match_to_user.json")
+
+	explanation, err := ExplainMatchToUser(context.Background(), client, testAnn, testOther[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if explanation.Reason == "" || len(explanation.ConversationStarters) != 3 {
+		t.Errorf("got %+v", explanation)
+	}
+	if len(explanation.SharedInterests) != 1 || explanation.SharedInterests[0].Interest != "hiking" {
+		t.Errorf("got shared interests %+v, want hiking", explanation.SharedInterests)
+	}
+}
+
+func TestBreakTie(t *testing.T) {
+	chosen := breakTie(testAnn, testOther, []string{"user-di", "user-bo"}, compatibility.DefaultWeights)
+	if chosen == nil || chosen.Id != "user-bo" {
+		t.Fatalf("got %+v, want the better scoring user-bo", chosen)
+	}
+
+	chosen = breakTie(testAnn, testOther, []string{"user-di"}, compatibility.DefaultWeights)
+	if chosen == nil || chosen.Id != "user-di" {
+		t.Fatalf("got %+v, want the only named user", chosen)
+	}
+
+	if chosen := breakTie(testAnn, testOther, []string{"user-zed"}, compatibility.DefaultWeights); chosen != nil {
+		t.Fatalf("got %+v for an unknown id", chosen)
+	}
+}
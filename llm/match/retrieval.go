@@ -0,0 +1,102 @@
+package match
+
+import (
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/nvdaz/find-a-friend-api/model"
+	"github.com/nvdaz/find-a-friend-api/vector"
+)
+
+const DefaultRetrievalTopN = 20
+
+// Retrieval narrows the pool of users sent to the LLM stage to the ones whose
+// embedded profiles are closest to the user's.
+type Retrieval struct {
+	Index *vector.Index
+	TopN  int
+}
+
+func NewRetrieval(index *vector.Index, topN int) Retrieval {
+	if topN <= 0 {
+		topN = DefaultRetrievalTopN
+	}
+
+	return Retrieval{index, topN}
+}
+
+func NewRetrievalFromEnv(index *vector.Index) Retrieval {
+	topN, _ := strconv.Atoi(os.Getenv("MATCH_RETRIEVAL_TOP_N"))
+
+	return NewRetrieval(index, topN)
+}
+
+type similarity struct {
+	userKind  string
+	otherKind string
+	weight    float64
+}
+
+// What the user is looking for is compared against who the other user is,
+// and the other way around.
+var similarities = []similarity{
+	{vector.KindSummary, vector.KindSummary, 0.3},
+	{vector.KindInterests, vector.KindInterests, 0.3},
+	{vector.KindLookingFor, vector.KindSummary, 0.2},
+	{vector.KindSummary, vector.KindLookingFor, 0.2},
+}
+
+// Rank returns at most TopN users: the most similar first, then users that
+// have not been embedded yet in pool order, so new users still get a chance at
+// matching. Pools no larger than TopN are passed through as is.
+func (retrieval Retrieval) Rank(user model.User, users []model.User) []model.User {
+	if retrieval.Index == nil || len(users) <= retrieval.TopN {
+		return users
+	}
+
+	pool := make(map[string]bool, len(users))
+	for _, u := range users {
+		pool[u.Id] = true
+	}
+	inPool := func(id string) bool {
+		return pool[id]
+	}
+
+	scores := map[string]float64{}
+	weights := map[string]float64{}
+	for _, s := range similarities {
+		query, ok := retrieval.Index.Get(vector.Key{Kind: s.userKind, Id: user.Id})
+		if !ok {
+			continue
+		}
+
+		for _, result := range retrieval.Index.Search(s.otherKind, query, 0, inPool) {
+			scores[result.Id] += s.weight * result.Score
+			weights[result.Id] += s.weight
+		}
+	}
+
+	if len(scores) == 0 {
+		return users[:retrieval.TopN]
+	}
+
+	var ranked, unembedded []model.User
+	for _, u := range users {
+		if weights[u.Id] == 0 {
+			unembedded = append(unembedded, u)
+		} else {
+			ranked = append(ranked, u)
+		}
+	}
+
+	score := func(id string) float64 {
+		return scores[id] / weights[id]
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return score(ranked[i].Id) > score(ranked[j].Id)
+	})
+	ranked = append(ranked, unembedded...)
+
+	return ranked[:retrieval.TopN]
+}
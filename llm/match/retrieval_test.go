@@ -0,0 +1,102 @@
+package match
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/model"
+	"github.com/nvdaz/find-a-friend-api/vector"
+)
+
+type memoryEmbeddings map[vector.Key]vector.Record
+
+func (store memoryEmbeddings) GetEmbeddings(model string) ([]vector.Record, error) {
+	records := []vector.Record{}
+	for _, record := range store {
+		if record.Model == model {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (store memoryEmbeddings) PutEmbedding(record vector.Record) error {
+	store[vector.Key{Kind: record.Kind, Id: record.Id}] = record
+	return nil
+}
+
+func (store memoryEmbeddings) DeleteEmbedding(kind, id string) error {
+	delete(store, vector.Key{Kind: kind, Id: id})
+	return nil
+}
+
+func (store memoryEmbeddings) DeleteEmbeddings(id string) error {
+	for key := range store {
+		if key.Id == id {
+			delete(store, key)
+		}
+	}
+	return nil
+}
+
+func retrievalIndex(t *testing.T, summaries map[string]string) *vector.Index {
+	index := vector.NewIndex(memoryEmbeddings{}, llm.NewClient(nil, llm.NewLocalEmbedder(64), nil))
+
+	items := []vector.Item{}
+	for id, summary := range summaries {
+		items = append(items, vector.Item{Key: vector.Key{Kind: vector.KindSummary, Id: id}, Text: summary})
+	}
+	if err := index.Put(context.Background(), items); err != nil {
+		t.Fatal(err)
+	}
+
+	return index
+}
+
+func ids(users []model.User) []string {
+	result := make([]string, len(users))
+	for i, u := range users {
+		result[i] = u.Id
+	}
+	return result
+}
+
+func TestRankKeepsTopNAndFillsWithUnembedded(t *testing.T) {
+	index := retrievalIndex(t, map[string]string{
+		"ann": "loves board games and hiking",
+		"bo":  "loves board games and strategy games",
+		"cy":  "loves hiking trails",
+		"di":  "plays jazz piano",
+	})
+	user := model.User{Id: "ann"}
+	pool := []model.User{{Id: "new-1"}, {Id: "di"}, {Id: "bo"}, {Id: "new-2"}, {Id: "cy"}, {Id: "new-3"}}
+
+	got := ids(NewRetrieval(index, 2).Rank(user, pool))
+	if len(got) != 2 || got[0] != "bo" || got[1] != "cy" {
+		t.Errorf("got %v, want the two most similar users", got)
+	}
+
+	got = ids(NewRetrieval(index, 5).Rank(user, pool))
+	want := []string{"bo", "cy", "di", "new-1", "new-2"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRankCapsUnembeddedPool(t *testing.T) {
+	index := retrievalIndex(t, map[string]string{"ann": "loves board games"})
+	pool := []model.User{{Id: "new-1"}, {Id: "new-2"}, {Id: "new-3"}}
+
+	if got := NewRetrieval(index, 2).Rank(model.User{Id: "ann"}, pool); len(got) != 2 {
+		t.Errorf("got %v, want at most 2 users", ids(got))
+	}
+	if got := NewRetrieval(index, 2).Rank(model.User{Id: "zed"}, pool); len(got) != 2 {
+		t.Errorf("got %v for a user without embeddings, want at most 2 users", ids(got))
+	}
+}
@@ -0,0 +1,110 @@
+package match
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+type userLookup struct {
+	Id string `json:"id"`
+}
+
+type profileSummary struct {
+	Id         string            `json:"id"`
+	Name       string            `json:"name"`
+	Summary    string            `json:"summary"`
+	LookingFor string            `json:"looking_for"`
+	Interests  []model.Interest  `json:"interests"`
+	Topics     []model.Topic     `json:"topics"`
+	Goals      []model.Goal      `json:"goals"`
+	Values     []model.CoreValue `json:"values"`
+}
+
+type interestComparison struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+type comparedInterests struct {
+	Shared []string `json:"shared"`
+	OnlyA  []string `json:"only_a"`
+	OnlyB  []string `json:"only_b"`
+}
+
+func findUser(users []model.User, id string) (*model.User, error) {
+	for i := range users {
+		if users[i].Id == id && users[i].Profile != nil {
+			return &users[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no user with id %s", id)
+}
+
+func interestNames(user *model.User) map[string]string {
+	names := map[string]string{}
+	for _, interest := range user.Profile.Interests {
+		names[strings.ToLower(interest.Interest)] = interest.Interest
+	}
+	for _, topic := range user.Profile.Topics {
+		names[strings.ToLower(topic.Topic)] = topic.Topic
+	}
+
+	return names
+}
+
+func matchTools(users []model.User) []llm.Tool {
+	lookupUserProfile := llm.NewTool("lookup_user_profile", "Look up the profile of a user by ID.",
+		func(ctx context.Context, arguments userLookup) (profileSummary, error) {
+			user, err := findUser(users, arguments.Id)
+			if err != nil {
+				return profileSummary{}, err
+			}
+
+			return profileSummary{
+				Id:         user.Id,
+				Name:       user.Name,
+				Summary:    user.Profile.Summary,
+				LookingFor: user.Profile.LookingFor,
+				Interests:  user.Profile.Interests,
+				Topics:     user.Profile.Topics,
+				Goals:      user.Profile.Goals,
+				Values:     user.Profile.Values,
+			}, nil
+		})
+
+	compareInterests := llm.NewTool("compare_interests", "Compare the interests and conversation topics of two users by ID.",
+		func(ctx context.Context, arguments interestComparison) (comparedInterests, error) {
+			a, err := findUser(users, arguments.A)
+			if err != nil {
+				return comparedInterests{}, err
+			}
+			b, err := findUser(users, arguments.B)
+			if err != nil {
+				return comparedInterests{}, err
+			}
+
+			aNames, bNames := interestNames(a), interestNames(b)
+			comparison := comparedInterests{Shared: []string{}, OnlyA: []string{}, OnlyB: []string{}}
+			for key, name := range aNames {
+				if _, ok := bNames[key]; ok {
+					comparison.Shared = append(comparison.Shared, name)
+				} else {
+					comparison.OnlyA = append(comparison.OnlyA, name)
+				}
+			}
+			for key, name := range bNames {
+				if _, ok := aNames[key]; !ok {
+					comparison.OnlyB = append(comparison.OnlyB, name)
+				}
+			}
+
+			return comparison, nil
+		})
+
+	return []llm.Tool{lookupUserProfile, compareInterests}
+}
@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type OllamaProvider struct {
+	baseUrl string
+	model   string
+	client  *http.Client
+}
+
+func NewOllamaProvider(baseUrl, model string) *OllamaProvider {
+	if baseUrl == "" {
+		baseUrl = "http://localhost:11434"
+	}
+	if model == "" {
+		model = "llama3"
+	}
+
+	return &OllamaProvider{strings.TrimSuffix(baseUrl, "/"), model, &http.Client{}}
+}
+
+func (provider *OllamaProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	options := map[string]any{}
+	if request.Temperature != nil {
+		options["temperature"] = *request.Temperature
+	}
+	if len(request.Stop) > 0 {
+		options["stop"] = request.Stop
+	}
+	if request.MaxTokens > 0 {
+		options["num_predict"] = request.MaxTokens
+	}
+
+	body := struct {
+		Model    string         `json:"model"`
+		Messages []Message      `json:"messages"`
+		Stream   bool           `json:"stream"`
+		Format   string         `json:"format,omitempty"`
+		Options  map[string]any `json:"options,omitempty"`
+	}{
+		Model:    provider.model,
+		Messages: request.Messages,
+		Stream:   false,
+		Options:  options,
+	}
+	if request.Json {
+		body.Format = "json"
+	}
+
+	var response struct {
+		Message         Message `json:"message"`
+		Error           string  `json:"error"`
+		PromptEvalCount int     `json:"prompt_eval_count"`
+		EvalCount       int     `json:"eval_count"`
+	}
+	if err := postJson(ctx, provider.client, provider.baseUrl+"/api/chat", nil, body, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != "" {
+		return nil, fmt.Errorf("response error: %s", response.Error)
+	}
+
+	return &Response{
+		Text: response.Message.Content,
+		Usage: Usage{
+			InputTokens:  response.PromptEvalCount,
+			OutputTokens: response.EvalCount,
+		},
+	}, nil
+}
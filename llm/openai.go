@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var openAIModels = map[Model]string{
+	ModelGpt4:         "gpt-4-turbo",
+	ModelGpt3p5:       "gpt-3.5-turbo",
+	ModelClaudeHaiku:  "gpt-3.5-turbo",
+	ModelClaudeSonnet: "gpt-4-turbo",
+}
+
+type OpenAIProvider struct {
+	baseUrl string
+	apiKey  string
+	client  *http.Client
+}
+
+func NewOpenAIProvider(baseUrl, apiKey string) *OpenAIProvider {
+	if baseUrl == "" {
+		baseUrl = "https://api.openai.com/v1"
+	}
+
+	return &OpenAIProvider{strings.TrimSuffix(baseUrl, "/"), apiKey, &http.Client{}}
+}
+
+type openAIResponseFormat struct {
+	Type string `json:"type"`
+}
+
+func (provider *OpenAIProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	body := struct {
+		Model          string                `json:"model"`
+		Messages       []Message             `json:"messages"`
+		Temperature    *float64              `json:"temperature,omitempty"`
+		Stop           []string              `json:"stop,omitempty"`
+		MaxTokens      int                   `json:"max_tokens,omitempty"`
+		ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	}{
+		Model:       mapModel(openAIModels, request.Model),
+		Messages:    request.Messages,
+		Temperature: request.Temperature,
+		Stop:        request.Stop,
+		MaxTokens:   request.MaxTokens,
+	}
+	if request.Json {
+		body.ResponseFormat = &openAIResponseFormat{"json_object"}
+	}
+
+	headers := map[string]string{}
+	if provider.apiKey != "" {
+		headers["Authorization"] = "Bearer " + provider.apiKey
+	}
+
+	var response struct {
+		Choices []struct {
+			Message Message `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := postJson(ctx, provider.client, provider.baseUrl+"/chat/completions", headers, body, &response); err != nil {
+		return nil, err
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
+	}
+
+	return &Response{
+		Text: response.Choices[0].Message.Content,
+		Usage: Usage{
+			InputTokens:  response.Usage.PromptTokens,
+			OutputTokens: response.Usage.CompletionTokens,
+		},
+	}, nil
+}
@@ -3,13 +3,12 @@ package profile
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/llm/prompt"
 	"github.com/nvdaz/find-a-friend-api/model"
 	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/semaphore"
 )
 
 const (
@@ -17,7 +16,9 @@ const (
 	UserKeyQuestionsCount = 3
 )
 
-func generateUserBio(user model.IntermediateProfile) (string, error) {
+func generateUserBio(ctx context.Context, client *llm.Client, user model.IntermediateProfile) (string, error) {
+	ctx = llm.WithFeature(ctx, "generateUserBio")
+
 	profileString, err := json.Marshal(user)
 	if err != nil {
 		return "", err
@@ -27,7 +28,12 @@ func generateUserBio(user model.IntermediateProfile) (string, error) {
 		Bio string `json:"bio"`
 	}{}
 
-	err = llm.GetResponseJson(&result, llm.ModelGpt4, string(profileString), "Create a short, passionate introductory biography in a casual, friendly tone from the perspective of the provided user using personal pronouns. Include a brief description of their personality and interests. The biography should be a single paragraph, no more than 120 words in length. Provide a JSON object without any formatting containing two keys: 'bio', with the value being the biography.", nil)
+	system, err := prompt.UserBio.Render(ctx, prompt.None{})
+	if err != nil {
+		return "", err
+	}
+
+	err = client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelGpt4, llm.ModelClaudeSonnet, llm.ModelGpt3p5}, string(profileString), system, nil)
 	if err != nil {
 		return "", err
 	}
@@ -35,15 +41,17 @@ func generateUserBio(user model.IntermediateProfile) (string, error) {
 	return result.Bio, nil
 }
 
-func generateUserKeyQuestions(user model.IntermediateProfile, questions string) ([]string, error) {
-	prompt := struct {
+func generateUserKeyQuestions(ctx context.Context, client *llm.Client, user model.IntermediateProfile, questions string) ([]string, error) {
+	ctx = llm.WithFeature(ctx, "generateUserKeyQuestions")
+
+	input := struct {
 		User      model.IntermediateProfile `json:"user"`
 		Questions string                    `json:"questions"`
 	}{
 		User:      user,
 		Questions: questions,
 	}
-	data, err := json.Marshal(prompt)
+	data, err := json.Marshal(input)
 	if err != nil {
 		return nil, err
 	}
@@ -52,7 +60,12 @@ func generateUserKeyQuestions(user model.IntermediateProfile, questions string)
 		Questions []string `json:"key_questions"`
 	}{}
 
-	err = llm.GetResponseJson(&result, llm.ModelClaudeSonnet, string(data), fmt.Sprintf("Create a list of %d key questions that the user has already asked the chat bot that are representative of their interests and selected to spark conversation. Provide a JSON object without any formatting containing a single key: 'key_questions', with the value being a list of the questions.", UserKeyQuestionsCount), nil)
+	system, err := prompt.UserKeyQuestions.Render(ctx, prompt.Count{Count: UserKeyQuestionsCount})
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, string(data), system, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +73,9 @@ func generateUserKeyQuestions(user model.IntermediateProfile, questions string)
 	return result.Questions, nil
 }
 
-func generateUserTags(user model.IntermediateProfile) ([]model.Tag, error) {
+func generateUserTags(ctx context.Context, client *llm.Client, user model.IntermediateProfile) ([]model.Tag, error) {
+	ctx = llm.WithFeature(ctx, "generateUserTags")
+
 	profileString, err := json.Marshal(user)
 	if err != nil {
 		return nil, err
@@ -70,7 +85,12 @@ func generateUserTags(user model.IntermediateProfile) ([]model.Tag, error) {
 		Tags []model.Tag `json:"tags"`
 	}{}
 
-	err = llm.GetResponseJson(&result, llm.ModelGpt4, string(profileString), fmt.Sprintf("Create a list of %d short tags that describe the user. The tags should be representative of who they are, but not restating what is already given (for example: analytical thinker, in college, ethical innovator). Provide a JSON object without any formatting containing a single key: 'tags', with the value being a list of tags. Each tag should have a key 'tag' with the tag name and a key 'emoji' with a single emoji to accompany it.", UserTagsCount), nil)
+	system, err := prompt.UserTags.Render(ctx, prompt.Count{Count: UserTagsCount})
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelGpt4, llm.ModelClaudeSonnet, llm.ModelGpt3p5}, string(profileString), system, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +98,9 @@ func generateUserTags(user model.IntermediateProfile) ([]model.Tag, error) {
 	return result.Tags, nil
 }
 
-func generateUserSummary(user model.IntermediateProfile) (string, error) {
+func generateUserSummary(ctx context.Context, client *llm.Client, user model.IntermediateProfile) (string, error) {
+	ctx = llm.WithFeature(ctx, "generateUserSummary")
+
 	profileString, err := json.Marshal(user)
 	if err != nil {
 		return "", err
@@ -88,7 +110,12 @@ func generateUserSummary(user model.IntermediateProfile) (string, error) {
 		Summary string `json:"summary"`
 	}{}
 
-	err = llm.GetResponseJson(&result, llm.ModelGpt4, string(profileString), "Create an in-depth summary of the user's profile including only the most important information about them. The summary should be no more than 120 words in length. Provide a JSON object without any formatting containing a single key: 'summary', with the value being the summary.", nil)
+	system, err := prompt.UserSummary.Render(ctx, prompt.None{})
+	if err != nil {
+		return "", err
+	}
+
+	err = client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelGpt4, llm.ModelClaudeSonnet, llm.ModelGpt3p5}, string(profileString), system, nil)
 	if err != nil {
 		return "", err
 	}
@@ -96,7 +123,9 @@ func generateUserSummary(user model.IntermediateProfile) (string, error) {
 	return result.Summary, nil
 }
 
-func generateUserSubtitle(user model.IntermediateProfile) (string, error) {
+func generateUserSubtitle(ctx context.Context, client *llm.Client, user model.IntermediateProfile) (string, error) {
+	ctx = llm.WithFeature(ctx, "generateUserSubtitle")
+
 	profileString, err := json.Marshal(user)
 	if err != nil {
 		return "", err
@@ -106,7 +135,12 @@ func generateUserSubtitle(user model.IntermediateProfile) (string, error) {
 		Subtitle string `json:"subtitle"`
 	}{}
 
-	err = llm.GetResponseJson(&result, llm.ModelGpt4, string(profileString), "Create a 2-6 word creative subtitle in a casual, friendly tone to go under the user's name under their profile that captures the essence of their personality. Be as unique and creative as possible. Dive into what cannot be immediately seen just by their profile. Provide a JSON object without any formatting containing a single key: 'subtitle', with the value being the subtitle", nil)
+	system, err := prompt.UserSubtitle.Render(ctx, prompt.None{})
+	if err != nil {
+		return "", err
+	}
+
+	err = client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelGpt4, llm.ModelClaudeSonnet, llm.ModelGpt3p5}, string(profileString), system, nil)
 	if err != nil {
 		return "", err
 	}
@@ -114,7 +148,9 @@ func generateUserSubtitle(user model.IntermediateProfile) (string, error) {
 	return result.Subtitle, nil
 }
 
-func generateUserLookingFor(user model.IntermediateProfile) (string, error) {
+func generateUserLookingFor(ctx context.Context, client *llm.Client, user model.IntermediateProfile) (string, error) {
+	ctx = llm.WithFeature(ctx, "generateUserLookingFor")
+
 	profileString, err := json.Marshal(user)
 	if err != nil {
 		return "", err
@@ -124,7 +160,12 @@ func generateUserLookingFor(user model.IntermediateProfile) (string, error) {
 		LookingFor string `json:"looking_for"`
 	}{}
 
-	err = llm.GetResponseJson(&result, llm.ModelGpt4, string(profileString), "Create a short, creative description (about 10-15 words) that expresses the kind of friend the user is looing for (for example: Like-minded girlfriends to share a love of books and coffee). Be as unique and creative as possible. Dive into what cannot be immediately seen just by their profile. Provide a JSON object without any formatting containing a single key: 'looking_for', with the value being the description.", nil)
+	system, err := prompt.UserLookingFor.Render(ctx, prompt.None{})
+	if err != nil {
+		return "", err
+	}
+
+	err = client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelGpt4, llm.ModelClaudeSonnet, llm.ModelGpt3p5}, string(profileString), system, nil)
 	if err != nil {
 		return "", err
 	}
@@ -133,11 +174,9 @@ func generateUserLookingFor(user model.IntermediateProfile) (string, error) {
 
 }
 
-func generateUserFeatures(user model.IntermediateProfile, questions string) (*model.ProfileFeatures, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-	group, _ := errgroup.WithContext(ctx)
-
-	sem := semaphore.NewWeighted(4)
+func generateUserFeatures(ctx context.Context, client *llm.Client, user model.IntermediateProfile, questions string) (*model.ProfileFeatures, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	group, groupCtx := errgroup.WithContext(ctx)
 	defer cancel()
 
 	var summary string
@@ -149,62 +188,32 @@ func generateUserFeatures(user model.IntermediateProfile, questions string) (*mo
 	var err error
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-
-		summary, err = generateUserSummary(user)
+		summary, err = generateUserSummary(groupCtx, client, user)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-
-		tags, err = generateUserTags(user)
+		tags, err = generateUserTags(groupCtx, client, user)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-
-		bio, err = generateUserBio(user)
+		bio, err = generateUserBio(groupCtx, client, user)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-
-		keyQuestions, err = generateUserKeyQuestions(user, questions)
+		keyQuestions, err = generateUserKeyQuestions(groupCtx, client, user, questions)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-
-		subtitle, err = generateUserSubtitle(user)
+		subtitle, err = generateUserSubtitle(groupCtx, client, user)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-
-		lookingFor, err = generateUserLookingFor(user)
+		lookingFor, err = generateUserLookingFor(groupCtx, client, user)
 		return err
 	})
 
@@ -1,19 +1,28 @@
 package profile
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/llm/prompt"
 	"github.com/nvdaz/find-a-friend-api/model"
 )
 
-func reviseProfile(user *model.IntermediateProfile) error {
+func reviseProfile(ctx context.Context, client *llm.Client, user *model.IntermediateProfile) error {
+	ctx = llm.WithFeature(ctx, "reviseProfile")
+
 	profileString, err := json.Marshal(user)
 	if err != nil {
 		return nil
 	}
 
-	err = llm.GetResponseJson(&user, llm.ModelGpt3p5, string(profileString), "Your job is to revise the profile, inferring any missing information. Respond with the updated profile in JSON format exactly in the format it was received.", nil)
+	system, err := prompt.ReviseProfile.Render(ctx, prompt.None{})
+	if err != nil {
+		return err
+	}
+
+	err = client.GetResponseJson(ctx, &user, llm.Chain{llm.ModelGpt3p5, llm.ModelClaudeHaiku}, string(profileString), system, nil)
 	if err != nil {
 		return err
 	}
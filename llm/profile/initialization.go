@@ -6,9 +6,9 @@ import (
 	"time"
 
 	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/llm/prompt"
 	"github.com/nvdaz/find-a-friend-api/model"
 	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/semaphore"
 )
 
 const (
@@ -21,13 +21,18 @@ const (
 	UserHobbiesCount     = 5
 )
 
-func initializeInterests(questions string) ([]model.Interest, error) {
-	system := fmt.Sprintf("Create a list of interests based on the provided chatbot questions. The list should contain %d specific interests. Provide a JSON object without any formatting containing the key 'interests', with the value being the list of interests. The interests should be objects with a key 'interest' containing the interest, a key 'level' containing the interest level on a scale of 0 to 1, and a key 'emoji' with a single, relevant emoji.", UserInterestsCount)
+func initializeInterests(ctx context.Context, client *llm.Client, questions string) ([]model.Interest, error) {
+	ctx = llm.WithFeature(ctx, "initializeInterests")
+
+	system, err := prompt.InitializeInterests.Render(ctx, prompt.Count{Count: UserInterestsCount})
+	if err != nil {
+		return nil, err
+	}
 
 	result := struct {
-		Interests []model.Interest `json:"interests"`
+		Interests []model.Interest `json:"interests" jsonschema:"minItems=1"`
 	}{}
-	if err := llm.GetResponseJson(&result, llm.ModelGpt4, questions, system, nil); err != nil {
+	if err := client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelGpt4, llm.ModelClaudeSonnet, llm.ModelGpt3p5}, questions, system, nil); err != nil {
 		return nil, err
 	}
 
@@ -38,24 +43,34 @@ func initializeInterests(questions string) ([]model.Interest, error) {
 	return result.Interests, nil
 }
 
-func initializePersonality(questions string) (model.Personality, error) {
-	system := "Let us play a guessing game. You are provided with a list of questions the user asked a chat bot. Guess the user's personality based on the Big Five (OCEAN) model, assigning scores from 0 to 5 for each trait, where 0 means the trait is not present and 5 signifies a strong presence. List the scores for Openness, Conscientiousness, Extroersion, Agreeableness, and Neuroticism. Start with analysis and use deductive reasoning to answer as precisely as possible. Then provide a JSON object in a JSON code block containing the keys 'openness', 'conscientiousness', 'extroversion', 'agreeableness', and 'neuroticism'."
+func initializePersonality(ctx context.Context, client *llm.Client, questions string) (model.Personality, error) {
+	ctx = llm.WithFeature(ctx, "initializePersonality")
+
+	system, err := prompt.InitializePersonality.Render(ctx, prompt.None{})
+	if err != nil {
+		return model.Personality{}, err
+	}
 
 	result := model.Personality{}
-	if err := llm.GetResponseJson(&result, llm.ModelClaudeSonnet, questions, system, nil); err != nil {
+	if err := client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, questions, system, nil); err != nil {
 		return model.Personality{}, err
 	}
 
 	return result, nil
 }
 
-func initializeSkills(questions string) ([]model.Skill, error) {
-	system := fmt.Sprintf("Create a list of the user's skills based on the provided chatbot questions. The list should contain %d specific skills. Provide a JSON object without any formatting containing the key 'skills', with the value being the list of skills. The skills should be objects with a key 'skill' containing the skill and a key 'level' containing the skill level on a scale of 0 to 1.", UserSkillsCount)
+func initializeSkills(ctx context.Context, client *llm.Client, questions string) ([]model.Skill, error) {
+	ctx = llm.WithFeature(ctx, "initializeSkills")
+
+	system, err := prompt.InitializeSkills.Render(ctx, prompt.Count{Count: UserSkillsCount})
+	if err != nil {
+		return nil, err
+	}
 
 	result := struct {
-		Skills []model.Skill `json:"skills"`
+		Skills []model.Skill `json:"skills" jsonschema:"minItems=1"`
 	}{}
-	if err := llm.GetResponseJson(&result, llm.ModelClaudeSonnet, questions, system, nil); err != nil {
+	if err := client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, questions, system, nil); err != nil {
 		return nil, err
 	}
 
@@ -66,13 +81,18 @@ func initializeSkills(questions string) ([]model.Skill, error) {
 	return result.Skills, nil
 }
 
-func initializeGoals(questions string) ([]model.Goal, error) {
-	system := fmt.Sprintf("Let us play a guessing game. You are provided a list of chatbot questions a user asked. Guess the ambitions and goals that the user has. The list should contain %d specific goals. Describe goals in terse terms. Start with analysis and use deductive reasoning to answer as precisely as possible. Provide a JSON object containing the key 'goals', with the value being the list of goals. Each goal should be an object with a key 'goal' containing the goal and a key 'importance' containing the importance to the user on a scale from 0 to 1.", UserGoalsCount)
+func initializeGoals(ctx context.Context, client *llm.Client, questions string) ([]model.Goal, error) {
+	ctx = llm.WithFeature(ctx, "initializeGoals")
+
+	system, err := prompt.InitializeGoals.Render(ctx, prompt.Count{Count: UserGoalsCount})
+	if err != nil {
+		return nil, err
+	}
 
 	result := struct {
-		Goals []model.Goal `json:"goals"`
+		Goals []model.Goal `json:"goals" jsonschema:"minItems=1"`
 	}{}
-	if err := llm.GetResponseJson(&result, llm.ModelClaudeSonnet, questions, system, nil); err != nil {
+	if err := client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, questions, system, nil); err != nil {
 		return nil, err
 	}
 
@@ -83,13 +103,18 @@ func initializeGoals(questions string) ([]model.Goal, error) {
 	return result.Goals, nil
 }
 
-func initializeValues(questions string) ([]model.CoreValue, error) {
-	system := fmt.Sprintf("Create a list of the user's values and worldviews based on the provided chatbot questions. The list should contain %d specific values. Provide a JSON object in a JSON code block containing the key 'core_values', with the value being the list of values. Each value should be an object with a key 'value' containing the specific value and a key 'importance' containing the importance to the user on a scale from 0 to 1. Start with an in-depth analysis of the user's queries in an 'analysis' key.", UserValuesCount)
+func initializeValues(ctx context.Context, client *llm.Client, questions string) ([]model.CoreValue, error) {
+	ctx = llm.WithFeature(ctx, "initializeValues")
+
+	system, err := prompt.InitializeValues.Render(ctx, prompt.Count{Count: UserValuesCount})
+	if err != nil {
+		return nil, err
+	}
 
 	result := struct {
-		Values []model.CoreValue `json:"core_values"`
+		Values []model.CoreValue `json:"core_values" jsonschema:"minItems=1"`
 	}{}
-	if err := llm.GetResponseJson(&result, llm.ModelClaudeSonnet, questions, system, nil); err != nil {
+	if err := client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, questions, system, nil); err != nil {
 		return nil, err
 	}
 
@@ -101,49 +126,69 @@ func initializeValues(questions string) ([]model.CoreValue, error) {
 
 }
 
-func initializeDemographics(questions string) (model.Demographics, error) {
-	system := "Let us play a guessing game. You are provided with a list of questions a user asked to a chatbot. Your task is to guess the user's demographic profile. Start with analysis and use deductive reasoning to answer as precisely as possible. Then, provide a valid JSON object in a JSON code block, with keys 'age', 'gender', 'location', 'occupation', 'highest_education', 'living_status', 'political_affiliation', 'religious_affiliation', 'nationality', 'spoken_languages' (list), and 'social_class'. Never reply with uncertainty; this is a game of deduction and analysis. Always provide a complete profile."
+func initializeDemographics(ctx context.Context, client *llm.Client, questions string) (model.Demographics, error) {
+	ctx = llm.WithFeature(ctx, "initializeDemographics")
+
+	system, err := prompt.InitializeDemographics.Render(ctx, prompt.None{})
+	if err != nil {
+		return model.Demographics{}, err
+	}
 	result := model.Demographics{}
-	if err := llm.GetResponseJson(&result, llm.ModelClaudeSonnet, questions, system, nil); err != nil {
+	if err := client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, questions, system, nil); err != nil {
 		return model.Demographics{}, err
 	}
 
 	return result, nil
 }
 
-func initializeLivedExperiences(questions string) ([]string, error) {
-	system := fmt.Sprintf("You are provided a list of questions a user asked to a chatbot. Create a list of %d specific lived experiences the user has had. Provide a JSON object without any formatting containing the key 'lived_experiences', with the value being the list of experiences.", UserExperiencesCount)
+func initializeLivedExperiences(ctx context.Context, client *llm.Client, questions string) ([]string, error) {
+	ctx = llm.WithFeature(ctx, "initializeLivedExperiences")
+
+	system, err := prompt.InitializeLivedExperiences.Render(ctx, prompt.Count{Count: UserExperiencesCount})
+	if err != nil {
+		return nil, err
+	}
 
 	result := struct {
 		LivedExperiences []string `json:"lived_experiences"`
 	}{}
-	if err := llm.GetResponseJson(&result, llm.ModelClaudeSonnet, questions, system, nil); err != nil {
+	if err := client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, questions, system, nil); err != nil {
 		return nil, err
 	}
 
 	return result.LivedExperiences, nil
 }
 
-func initializeHabits(questions string) ([]string, error) {
-	system := fmt.Sprintf("Create a list of %d specific habits based on the provided chatbot questions. Provide a JSON object without any formatting containing the key 'habits', with the value being the list of habits.", UserHabitsCount)
+func initializeHabits(ctx context.Context, client *llm.Client, questions string) ([]string, error) {
+	ctx = llm.WithFeature(ctx, "initializeHabits")
+
+	system, err := prompt.InitializeHabits.Render(ctx, prompt.Count{Count: UserHabitsCount})
+	if err != nil {
+		return nil, err
+	}
 
 	result := struct {
 		Habits []string `json:"habits"`
 	}{}
-	if err := llm.GetResponseJson(&result, llm.ModelClaudeSonnet, questions, system, nil); err != nil {
+	if err := client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, questions, system, nil); err != nil {
 		return nil, err
 	}
 
 	return result.Habits, nil
 }
 
-func initializeHobbies(questions string) ([]string, error) {
-	system := fmt.Sprintf("Create a list of %d specific hobbies that the user does for fun in the form of verb phrases based on the provided chatbot questions. Provide a JSON object without any formatting containing the key 'hobbies', with the value being the list of hobbies.", UserHobbiesCount)
+func initializeHobbies(ctx context.Context, client *llm.Client, questions string) ([]string, error) {
+	ctx = llm.WithFeature(ctx, "initializeHobbies")
+
+	system, err := prompt.InitializeHobbies.Render(ctx, prompt.Count{Count: UserHobbiesCount})
+	if err != nil {
+		return nil, err
+	}
 
 	result := struct {
 		Hobbies []string `json:"hobbies"`
 	}{}
-	if err := llm.GetResponseJson(&result, llm.ModelClaudeSonnet, questions, system, nil); err != nil {
+	if err := client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, questions, system, nil); err != nil {
 		return nil, err
 	}
 
@@ -151,24 +196,34 @@ func initializeHobbies(questions string) ([]string, error) {
 
 }
 
-func initializeInterpersonalSkills(questions string) (model.InterpersonalSkills, error) {
-	system := "Let us play a guessing game. You are provided with a list of questions a user asked to a chat bot. Guess their interpersonal skills. Start with analysis and use deductive reasoning to answer as precisely as possible. Then, provide a JSON object without any formatting containing the keys 'active_listening', 'teamwork', 'responsibility', 'dependability', 'leadership', 'motivation', 'flexibility', 'patience', and 'empathy'. Each key should have a value between 0 and 1, representing the strength of the skill."
+func initializeInterpersonalSkills(ctx context.Context, client *llm.Client, questions string) (model.InterpersonalSkills, error) {
+	ctx = llm.WithFeature(ctx, "initializeInterpersonalSkills")
+
+	system, err := prompt.InitializeInterpersonalSkills.Render(ctx, prompt.None{})
+	if err != nil {
+		return model.InterpersonalSkills{}, err
+	}
 
 	result := model.InterpersonalSkills{}
-	if err := llm.GetResponseJson(&result, llm.ModelClaudeSonnet, questions, system, nil); err != nil {
+	if err := client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, questions, system, nil); err != nil {
 		return model.InterpersonalSkills{}, err
 	}
 
 	return result, nil
 }
 
-func initializeExceptionalCircumstances(questions string) ([]string, error) {
-	system := "Analyze the questions the user asked to identify any potential challenges or conditions they may have mentioned, such as disabilities or autism. Provide a JSON object, formatted properly, with the key 'exceptional_circumstances'. The value should be a list of these challenges, if any are mentioned. If no specific challenges are mentioned, the list should be empty. Start with an in-depth analysis of the user's queries in an 'analysis' key. "
+func initializeExceptionalCircumstances(ctx context.Context, client *llm.Client, questions string) ([]string, error) {
+	ctx = llm.WithFeature(ctx, "initializeExceptionalCircumstances")
+
+	system, err := prompt.InitializeExceptionalCircumstances.Render(ctx, prompt.None{})
+	if err != nil {
+		return nil, err
+	}
 
 	result := struct {
 		ExceptionalCircumstances []string `json:"exceptional_circumstances"`
 	}{}
-	if err := llm.GetResponseJson(&result, llm.ModelClaudeSonnet, questions, system, nil); err != nil {
+	if err := client.GetResponseJson(ctx, &result, llm.Chain{llm.ModelClaudeSonnet, llm.ModelClaudeHaiku, llm.ModelGpt3p5}, questions, system, nil); err != nil {
 		return nil, err
 	}
 
@@ -176,11 +231,9 @@ func initializeExceptionalCircumstances(questions string) ([]string, error) {
 
 }
 
-func initializeProfile(id string, questions string, conversations string) (*model.IntermediateProfile, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-	group, _ := errgroup.WithContext(ctx)
-
-	sem := semaphore.NewWeighted(4)
+func initializeProfile(ctx context.Context, client *llm.Client, id string, questions string, conversations string) (*model.IntermediateProfile, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	group, groupCtx := errgroup.WithContext(ctx)
 	defer cancel()
 
 	var interests []model.Interest
@@ -200,131 +253,72 @@ func initializeProfile(id string, questions string, conversations string) (*mode
 	var err error
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-		interests, err = initializeInterests(questions)
+		interests, err = initializeInterests(groupCtx, client, questions)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-		personality, err = initializePersonality(questions)
+		personality, err = initializePersonality(groupCtx, client, questions)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-		skills, err = initializeSkills(questions)
+		skills, err = initializeSkills(groupCtx, client, questions)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-		goals, err = initializeGoals(questions)
+		goals, err = initializeGoals(groupCtx, client, questions)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-		values, err = initializeValues(questions)
+		values, err = initializeValues(groupCtx, client, questions)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-		demographics, err = initializeDemographics(questions)
+		demographics, err = initializeDemographics(groupCtx, client, questions)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-		livedExperiences, err = initializeLivedExperiences(questions)
+		livedExperiences, err = initializeLivedExperiences(groupCtx, client, questions)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-		habits, err = initializeHabits(questions)
+		habits, err = initializeHabits(groupCtx, client, questions)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-		interpersonalSkills, err = initializeInterpersonalSkills(questions)
+		interpersonalSkills, err = initializeInterpersonalSkills(groupCtx, client, questions)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-		hobbies, err = initializeHobbies(questions)
+		hobbies, err = initializeHobbies(groupCtx, client, questions)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-		exceptionalCircumstances, err = initializeExceptionalCircumstances(questions)
+		exceptionalCircumstances, err = initializeExceptionalCircumstances(groupCtx, client, questions)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-
-		topics, err = GenerateTopicsFromConversations(conversations)
+		topics, err = GenerateTopicsFromConversations(groupCtx, client, conversations)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-
-		conversationPersonality, err = GeneratePersonalityFromConversations(id, conversations)
+		conversationPersonality, err = GeneratePersonalityFromConversations(groupCtx, client, id, conversations)
 		return err
 	})
 
 	group.Go(func() error {
-		if err = sem.Acquire(ctx, 1); err != nil {
-			return err
-		}
-		defer sem.Release(1)
-
-		conversationInterpersonalSkills, err = GenerateInterpersonalSkillsFromConversations(id, conversations)
+		conversationInterpersonalSkills, err = GenerateInterpersonalSkillsFromConversations(groupCtx, client, id, conversations)
 		return err
 	})
 
@@ -1,14 +1,16 @@
 package profile
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/llm"
 	"github.com/nvdaz/find-a-friend-api/model"
 )
 
-func GenerateProfile(id string, questions []string, conversations [][]db.Message) (*model.InternalProfile, error) {
+func GenerateProfile(ctx context.Context, client *llm.Client, id string, questions []string, conversations [][]db.Message) (*model.InternalProfile, error) {
 	data, err := json.Marshal(questions)
 	if err != nil {
 		return nil, err
@@ -28,7 +30,7 @@ func GenerateProfile(id string, questions []string, conversations [][]db.Message
 		return nil, err
 	}
 
-	intermediateProfile, err := initializeProfile(id, string(data), string(conversationData))
+	intermediateProfile, err := initializeProfile(ctx, client, id, string(data), string(conversationData))
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +40,7 @@ func GenerateProfile(id string, questions []string, conversations [][]db.Message
 	// 	return nil, err
 	// }
 
-	features, err := generateUserFeatures(*intermediateProfile, string(data))
+	features, err := generateUserFeatures(ctx, client, *intermediateProfile, string(data))
 	if err != nil {
 		return nil, err
 	}
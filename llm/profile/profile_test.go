@@ -0,0 +1,48 @@
+package profile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/llm/llmtest"
+)
+
+func TestGenerateProfile(t *testing.T) {
+	client := llmtest.Client(t, "testdata/generate_profile.json")
+
+	questions := []string{
+		"What's a good two-player board game for a rainy weekend?",
+		"Which day hikes near Boston have the best views in the fall?",
+		"How should I structure handlers and services in a Go web API?",
+	}
+	conversations := [][]db.Message{{
+		{SenderId: "user-1", ReceiverId: "user-2", Message: "Did you end up trying Patchwork?"},
+		{SenderId: "user-2", ReceiverId: "user-1", Message: "Yes! We played it three times in a row."},
+		{SenderId: "user-1", ReceiverId: "user-2", Message: "Next up is Jaipur, then a hike on Sunday?"},
+	}}
+
+	profile, err := GenerateProfile(context.Background(), client, "user-1", questions, conversations)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(profile.Interests) == 0 || profile.Interests[0].Interest != "board games" {
+		t.Errorf("got interests %+v", profile.Interests)
+	}
+	if profile.Personality.Openness != 4 || profile.Personality.Neuroticism != 2 {
+		t.Errorf("got personality %+v", profile.Personality)
+	}
+	if profile.Demographics.AgeRange != "25-34" || len(profile.Demographics.SpokenLanguages) != 1 {
+		t.Errorf("got demographics %+v", profile.Demographics)
+	}
+	if len(profile.Values) != 2 || len(profile.Goals) != 2 || len(profile.Hobbies) != 2 {
+		t.Errorf("got values %+v, goals %+v, hobbies %+v", profile.Values, profile.Goals, profile.Hobbies)
+	}
+	if profile.Bio == "" || profile.Summary == "" || profile.Subtitle == "" || profile.LookingFor == "" {
+		t.Errorf("missing features in %+v", profile)
+	}
+	if len(profile.Tags) != 2 || len(profile.KeyQuestions) != 1 {
+		t.Errorf("got tags %+v, key questions %+v", profile.Tags, profile.KeyQuestions)
+	}
+}
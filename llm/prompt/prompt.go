@@ -0,0 +1,128 @@
+package prompt
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+type version struct {
+	number   int
+	template *template.Template
+}
+
+// Templates are named <name>.v<version>.tmpl; the highest version of each
+// name is the one rendered.
+var registry = loadRegistry()
+
+func loadRegistry() map[string]version {
+	files, err := fs.Glob(templateFiles, "templates/*.tmpl")
+	if err != nil {
+		panic(err)
+	}
+
+	registry := map[string]version{}
+	for _, file := range files {
+		name, suffix, ok := strings.Cut(strings.TrimSuffix(path.Base(file), ".tmpl"), ".v")
+		if !ok {
+			panic(fmt.Sprintf("prompt template %s is missing a version", file))
+		}
+
+		number, err := strconv.Atoi(suffix)
+		if err != nil {
+			panic(fmt.Sprintf("prompt template %s has an invalid version", file))
+		}
+
+		if existing, ok := registry[name]; ok && existing.number > number {
+			continue
+		}
+
+		data, err := templateFiles.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+
+		registry[name] = version{
+			number:   number,
+			template: template.Must(template.New(file).Option("missingkey=error").Parse(string(data))),
+		}
+	}
+
+	return registry
+}
+
+type Template[P any] struct {
+	name string
+}
+
+func define[P any](name string) Template[P] {
+	if _, ok := registry[name]; !ok {
+		panic(fmt.Sprintf("no prompt template named %s", name))
+	}
+
+	return Template[P]{name}
+}
+
+func (t Template[P]) Name() string {
+	return t.name
+}
+
+func (t Template[P]) Version() string {
+	return fmt.Sprintf("v%d", registry[t.name].number)
+}
+
+func (t Template[P]) Render(ctx context.Context, params P) (string, error) {
+	var text strings.Builder
+	if err := registry[t.name].template.Execute(&text, params); err != nil {
+		return "", fmt.Errorf("render prompt %s: %w", t.name, err)
+	}
+
+	if recorder, ok := ctx.Value(recorderKey{}).(*Recorder); ok {
+		recorder.record(t.name, t.Version())
+	}
+
+	return strings.TrimSpace(text.String()), nil
+}
+
+type Recorder struct {
+	mu       sync.Mutex
+	versions map[string]string
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{versions: map[string]string{}}
+}
+
+type recorderKey struct{}
+
+func WithRecorder(ctx context.Context, recorder *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, recorder)
+}
+
+func (recorder *Recorder) record(name, version string) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.versions[name] = version
+}
+
+func (recorder *Recorder) Versions() map[string]string {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	versions := make(map[string]string, len(recorder.versions))
+	for name, version := range recorder.versions {
+		versions[name] = version
+	}
+
+	return versions
+}
@@ -0,0 +1,54 @@
+package prompt
+
+type None struct{}
+
+type Count struct {
+	Count int
+}
+
+type User struct {
+	UserId string
+}
+
+type Pair struct {
+	UserName  string
+	OtherName string
+}
+
+type Member struct {
+	UserName string
+}
+
+var (
+	InitializeInterests                = define[Count]("initialize_interests")
+	InitializePersonality              = define[None]("initialize_personality")
+	InitializeSkills                   = define[Count]("initialize_skills")
+	InitializeGoals                    = define[Count]("initialize_goals")
+	InitializeValues                   = define[Count]("initialize_values")
+	InitializeDemographics             = define[None]("initialize_demographics")
+	InitializeLivedExperiences         = define[Count]("initialize_lived_experiences")
+	InitializeHabits                   = define[Count]("initialize_habits")
+	InitializeHobbies                  = define[Count]("initialize_hobbies")
+	InitializeInterpersonalSkills      = define[None]("initialize_interpersonal_skills")
+	InitializeExceptionalCircumstances = define[None]("initialize_exceptional_circumstances")
+
+	ConversationPersonality         = define[User]("conversation_personality")
+	ConversationInterpersonalSkills = define[User]("conversation_interpersonal_skills")
+	ConversationTopics              = define[None]("conversation_topics")
+
+	UserBio          = define[None]("user_bio")
+	UserKeyQuestions = define[Count]("user_key_questions")
+	UserTags         = define[Count]("user_tags")
+	UserSummary      = define[None]("user_summary")
+	UserSubtitle     = define[None]("user_subtitle")
+	UserLookingFor   = define[None]("user_looking_for")
+	ReviseProfile    = define[None]("revise_profile")
+
+	CandidateMatches   = define[Count]("candidate_matches")
+	ExplainMatch       = define[None]("explain_match")
+	DecideBestMatch    = define[None]("decide_best_match")
+	ExplainMatchToUser = define[Pair]("explain_match_to_user")
+
+	IntroduceGroup     = define[Count]("introduce_group")
+	ExplainGroupToUser = define[Member]("explain_group_to_user")
+)
@@ -0,0 +1,180 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+type Message struct {
+	Role    Role   `json:"role"`
+	Content string `json:"content"`
+}
+
+func SystemMessage(content string) Message {
+	return Message{RoleSystem, content}
+}
+
+func UserMessage(content string) Message {
+	return Message{RoleUser, content}
+}
+
+func AssistantMessage(content string) Message {
+	return Message{RoleAssistant, content}
+}
+
+type Options struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	// Json asks the model for a bare JSON object, using the provider's JSON
+	// mode where it has one.
+	Json bool `json:"json,omitempty"`
+}
+
+type Request struct {
+	Model    Model     `json:"model"`
+	Messages []Message `json:"messages"`
+	Options
+}
+
+// System joins the system messages for providers that take a single system
+// prompt.
+func (request Request) System() string {
+	var parts []string
+	for _, message := range request.Messages {
+		if message.Role == RoleSystem {
+			parts = append(parts, message.Content)
+		}
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+func (request Request) Turns() []Message {
+	var turns []Message
+	for _, message := range request.Messages {
+		if message.Role != RoleSystem {
+			turns = append(turns, message)
+		}
+	}
+
+	return turns
+}
+
+// Transcript flattens the conversation into a single prompt for providers
+// that only accept one. A lone user turn is passed through unchanged.
+func (request Request) Transcript() string {
+	turns := request.Turns()
+	if len(turns) == 1 && turns[0].Role == RoleUser {
+		return turns[0].Content
+	}
+
+	var transcript strings.Builder
+	for i, turn := range turns {
+		if i > 0 {
+			transcript.WriteString("\n\n")
+		}
+		if turn.Role == RoleAssistant {
+			transcript.WriteString("Assistant: ")
+		} else {
+			transcript.WriteString("User: ")
+		}
+		transcript.WriteString(turn.Content)
+	}
+
+	return transcript.String()
+}
+
+func truncateAtStop(text string, stop []string) string {
+	for _, sequence := range stop {
+		if i := strings.Index(text, sequence); sequence != "" && i != -1 {
+			text = text[:i]
+		}
+	}
+
+	return text
+}
+
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+type Response struct {
+	Text  string
+	Usage Usage
+}
+
+type Provider interface {
+	Complete(ctx context.Context, request Request) (*Response, error)
+}
+
+const (
+	ProviderWebsocket = "websocket"
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+)
+
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+func NewProviderFromEnv() (Provider, error) {
+	mode := os.Getenv("LLM_CASSETTE_MODE")
+	if mode == "" {
+		return newBaseProviderFromEnv()
+	}
+
+	path := os.Getenv("LLM_CASSETTE_PATH")
+
+	switch mode {
+	case CassetteRecord:
+		cassette, err := OpenCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		provider, err := newBaseProviderFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewRecordingProvider(provider, cassette), nil
+	case CassetteReplay:
+		cassette, err := LoadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewReplayProvider(cassette), nil
+	default:
+		return nil, fmt.Errorf("unknown llm cassette mode: %s", mode)
+	}
+}
+
+func newBaseProviderFromEnv() (Provider, error) {
+	switch name := os.Getenv("LLM_PROVIDER"); name {
+	case "", ProviderWebsocket:
+		poolSize, _ := strconv.Atoi(os.Getenv("LLM_WEBSOCKET_POOL_SIZE"))
+		maxInFlight, _ := strconv.Atoi(os.Getenv("LLM_WEBSOCKET_MAX_IN_FLIGHT"))
+		return NewWebsocketProvider(os.Getenv("LLM_WEBSOCKET_URI"), poolSize, maxInFlight), nil
+	case ProviderOpenAI:
+		return NewOpenAIProvider(os.Getenv("OPENAI_BASE_URL"), os.Getenv("OPENAI_API_KEY")), nil
+	case ProviderAnthropic:
+		return NewAnthropicProvider(os.Getenv("ANTHROPIC_BASE_URL"), os.Getenv("ANTHROPIC_API_KEY")), nil
+	case ProviderOllama:
+		return NewOllamaProvider(os.Getenv("OLLAMA_BASE_URL"), os.Getenv("OLLAMA_MODEL")), nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider: %s", name)
+	}
+}
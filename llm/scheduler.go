@@ -0,0 +1,319 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Priority int
+
+const (
+	PriorityInteractive Priority = iota
+	PriorityBackground
+	priorityCount
+)
+
+func (priority Priority) String() string {
+	if priority == PriorityInteractive {
+		return "interactive"
+	}
+
+	return "background"
+}
+
+type priorityKey struct{}
+
+// Work runs at background priority unless a caller marks it interactive.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func PriorityFrom(ctx context.Context) Priority {
+	priority, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok {
+		return PriorityBackground
+	}
+
+	return priority
+}
+
+var ErrRateLimited = errors.New("timed out waiting for llm capacity")
+
+type ModelLimit struct {
+	RequestsPerMinute float64
+	MaxConcurrent     int
+}
+
+var defaultModelLimits = map[Model]ModelLimit{
+	ModelGpt4:         {RequestsPerMinute: 60, MaxConcurrent: 8},
+	ModelGpt3p5:       {RequestsPerMinute: 300, MaxConcurrent: 16},
+	ModelClaudeHaiku:  {RequestsPerMinute: 300, MaxConcurrent: 16},
+	ModelClaudeSonnet: {RequestsPerMinute: 60, MaxConcurrent: 8},
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+type limiter struct {
+	mu       sync.Mutex
+	limit    ModelLimit
+	tokens   float64
+	refilled time.Time
+	inFlight int
+	queues   [priorityCount][]*waiter
+	timer    *time.Timer
+}
+
+func newLimiter(limit ModelLimit) *limiter {
+	return &limiter{limit: limit, tokens: limit.burst(), refilled: time.Now()}
+}
+
+func (limit ModelLimit) burst() float64 {
+	return max(1, limit.RequestsPerMinute/60)
+}
+
+// acquire waits for a slot. It fails with the context's error when the
+// caller gives up, and with ErrRateLimited when the wait times out.
+func (l *limiter) acquire(ctx context.Context, priority Priority) error {
+	w := &waiter{ready: make(chan struct{})}
+
+	l.mu.Lock()
+	l.queues[priority] = append(l.queues[priority], w)
+	l.dispatch()
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if w.granted {
+		l.inFlight--
+		l.dispatch()
+	} else {
+		queue := l.queues[priority]
+		for i, queued := range queue {
+			if queued == w {
+				l.queues[priority] = append(queue[:i:i], queue[i+1:]...)
+				break
+			}
+		}
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrRateLimited, ctx.Err())
+	}
+	return ctx.Err()
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	l.dispatch()
+}
+
+// dispatch hands out slots to queued waiters, highest priority first. It must
+// be called with l.mu held.
+func (l *limiter) dispatch() {
+	for {
+		priority := -1
+		for p := range l.queues {
+			if len(l.queues[p]) > 0 {
+				priority = p
+				break
+			}
+		}
+		if priority == -1 {
+			return
+		}
+
+		if l.limit.MaxConcurrent > 0 && l.inFlight >= l.limit.MaxConcurrent {
+			return
+		}
+
+		if l.limit.RequestsPerMinute > 0 {
+			now := time.Now()
+			l.tokens = min(l.limit.burst(), l.tokens+now.Sub(l.refilled).Minutes()*l.limit.RequestsPerMinute)
+			l.refilled = now
+
+			if l.tokens < 1 {
+				if l.timer == nil {
+					wait := time.Duration((1 - l.tokens) / l.limit.RequestsPerMinute * float64(time.Minute))
+					l.timer = time.AfterFunc(wait, func() {
+						l.mu.Lock()
+						defer l.mu.Unlock()
+
+						l.timer = nil
+						l.dispatch()
+					})
+				}
+				return
+			}
+			l.tokens--
+		}
+
+		w := l.queues[priority][0]
+		l.queues[priority] = l.queues[priority][1:]
+		l.inFlight++
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+type SchedulingProvider struct {
+	provider Provider
+	mu       sync.Mutex
+	limits   map[Model]ModelLimit
+	limiters map[Model]*limiter
+}
+
+func NewSchedulingProvider(provider Provider, limits map[Model]ModelLimit) *SchedulingProvider {
+	merged := map[Model]ModelLimit{}
+	for model, limit := range defaultModelLimits {
+		merged[model] = limit
+	}
+	for model, limit := range limits {
+		merged[model] = limit
+	}
+
+	scheduler := &SchedulingProvider{provider: provider, limits: merged, limiters: map[Model]*limiter{}}
+	schedulers.Set(scheduler)
+
+	return scheduler
+}
+
+// LLM_RATE_LIMITS overrides the per-model defaults as a comma separated list
+// of model=requests_per_minute/max_concurrent entries.
+func NewSchedulingProviderFromEnv(provider Provider) (*SchedulingProvider, error) {
+	limits, err := parseModelLimits(os.Getenv("LLM_RATE_LIMITS"))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSchedulingProvider(provider, limits), nil
+}
+
+func parseModelLimits(value string) (map[Model]ModelLimit, error) {
+	limits := map[Model]ModelLimit{}
+	if value == "" {
+		return limits, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		model, limit, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit entry %q", entry)
+		}
+
+		rate, concurrency, ok := strings.Cut(limit, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit entry %q", entry)
+		}
+
+		requestsPerMinute, err := strconv.ParseFloat(rate, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit entry %q: %w", entry, err)
+		}
+
+		maxConcurrent, err := strconv.Atoi(concurrency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit entry %q: %w", entry, err)
+		}
+
+		limits[Model(model)] = ModelLimit{requestsPerMinute, maxConcurrent}
+	}
+
+	return limits, nil
+}
+
+func (provider *SchedulingProvider) limiter(model Model) *limiter {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	l, ok := provider.limiters[model]
+	if !ok {
+		l = newLimiter(provider.limits[model])
+		provider.limiters[model] = l
+	}
+
+	return l
+}
+
+func (provider *SchedulingProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	l := provider.limiter(request.Model)
+	if err := l.acquire(ctx, PriorityFrom(ctx)); err != nil {
+		return nil, err
+	}
+	defer l.release()
+
+	return provider.provider.Complete(ctx, request)
+}
+
+func (provider *SchedulingProvider) snapshot() any {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	type limiterStatus struct {
+		InFlight int            `json:"in_flight"`
+		Queued   map[string]int `json:"queued"`
+		Tokens   float64        `json:"tokens"`
+	}
+
+	statuses := map[Model]limiterStatus{}
+	for model, l := range provider.limiters {
+		l.mu.Lock()
+		status := limiterStatus{InFlight: l.inFlight, Queued: map[string]int{}, Tokens: l.tokens}
+		for priority, queue := range l.queues {
+			status.Queued[Priority(priority).String()] = len(queue)
+		}
+		l.mu.Unlock()
+		statuses[model] = status
+	}
+
+	return statuses
+}
+
+type schedulerRegistry struct {
+	mu        sync.Mutex
+	scheduler *SchedulingProvider
+}
+
+func (registry *schedulerRegistry) Set(scheduler *SchedulingProvider) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.scheduler = scheduler
+}
+
+func (registry *schedulerRegistry) snapshot() any {
+	registry.mu.Lock()
+	scheduler := registry.scheduler
+	registry.mu.Unlock()
+
+	if scheduler == nil {
+		return nil
+	}
+
+	return scheduler.snapshot()
+}
+
+var schedulers = &schedulerRegistry{}
+
+func init() {
+	expvar.Publish("llm_scheduler", expvar.Func(schedulers.snapshot))
+}
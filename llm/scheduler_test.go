@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquireWhenCallerCancels(t *testing.T) {
+	l := newLimiter(ModelLimit{MaxConcurrent: 1})
+	if err := l.acquire(context.Background(), PriorityInteractive); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := l.acquire(ctx, PriorityInteractive)
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, PriorityInteractive); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got %v, want ErrRateLimited", err)
+	}
+
+	l.release()
+	if len(l.queues[PriorityInteractive]) != 0 {
+		t.Fatal("abandoned waiters were left queued")
+	}
+	if err := l.acquire(context.Background(), PriorityInteractive); err != nil {
+		t.Fatalf("slot was not released: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+func SchemaFor(value any) *Schema {
+	return schemaForType(reflect.TypeOf(value))
+}
+
+func schemaForType(t reflect.Type) *Schema {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaForType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaForType(t.Elem())}
+	case reflect.Struct:
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, omitempty := jsonFieldName(field)
+			if name == "-" {
+				continue
+			}
+
+			fieldSchema := schemaForType(field.Type)
+			applySchemaTag(fieldSchema, field.Tag.Get("jsonschema"))
+			schema.Properties[name] = fieldSchema
+			if !omitempty {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		return schema
+	default:
+		return &Schema{}
+	}
+}
+
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+
+	return name, strings.Contains(options, "omitempty")
+}
+
+func applySchemaTag(schema *Schema, tag string) {
+	if tag == "" {
+		return
+	}
+
+	for _, option := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "minimum", "maximum":
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				panic(fmt.Sprintf("invalid jsonschema %s: %q", key, value))
+			}
+			if key == "minimum" {
+				schema.Minimum = &number
+			} else {
+				schema.Maximum = &number
+			}
+		case "minItems", "maxItems":
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				panic(fmt.Sprintf("invalid jsonschema %s: %q", key, value))
+			}
+			if key == "minItems" {
+				schema.MinItems = &count
+			} else {
+				schema.MaxItems = &count
+			}
+		case "enum":
+			schema.Enum = strings.Split(value, "|")
+		default:
+			panic(fmt.Sprintf("unknown jsonschema option: %q", key))
+		}
+	}
+}
+
+func (schema *Schema) String() string {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return "{}"
+	}
+
+	return string(data)
+}
+
+func (schema *Schema) Validate(value any) []string {
+	return schema.validate("$", value, nil)
+}
+
+func (schema *Schema) validate(path string, value any, errors []string) []string {
+	fail := func(format string, args ...any) []string {
+		return append(errors, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch schema.Type {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fail("expected a string, got %s", describeJson(value))
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, str) {
+			return fail("%q is not one of %s", str, strings.Join(schema.Enum, ", "))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("expected a boolean, got %s", describeJson(value))
+		}
+	case "integer", "number":
+		number, ok := value.(float64)
+		if !ok {
+			return fail("expected a number, got %s", describeJson(value))
+		}
+		if schema.Type == "integer" && number != float64(int64(number)) {
+			return fail("expected an integer, got %v", number)
+		}
+		if schema.Minimum != nil && number < *schema.Minimum {
+			return fail("%v is less than the minimum %v", number, *schema.Minimum)
+		}
+		if schema.Maximum != nil && number > *schema.Maximum {
+			return fail("%v is greater than the maximum %v", number, *schema.Maximum)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fail("expected an array, got %s", describeJson(value))
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			errors = fail("expected at least %d items, got %d", *schema.MinItems, len(items))
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			errors = fail("expected at most %d items, got %d", *schema.MaxItems, len(items))
+		}
+		for i, item := range items {
+			errors = schema.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errors)
+		}
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fail("expected an object, got %s", describeJson(value))
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				errors = fail("missing required key %q", name)
+			}
+		}
+
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			property, ok := schema.Properties[key]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			if property != nil {
+				errors = property.validate(path+"."+key, object[key], errors)
+			}
+		}
+	}
+
+	return errors
+}
+
+func describeJson(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "an object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
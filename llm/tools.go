@@ -0,0 +1,167 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrTooManySteps = errors.New("tool loop did not finish within its step limit")
+
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  *Schema
+	call        func(ctx context.Context, arguments json.RawMessage) (any, error)
+}
+
+// NewTool exposes fn to the model. The parameter schema is derived from A in
+// the same way as structured responses, and arguments are validated against
+// it before fn is called.
+func NewTool[A any, R any](name, description string, fn func(ctx context.Context, arguments A) (R, error)) Tool {
+	var zero A
+	schema := SchemaFor(zero)
+
+	return Tool{
+		Name:        name,
+		Description: description,
+		Parameters:  schema,
+		call: func(ctx context.Context, raw json.RawMessage) (any, error) {
+			var value any
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			if problems := schema.Validate(value); len(problems) > 0 {
+				return nil, &ValidationError{problems}
+			}
+
+			var arguments A
+			if err := json.Unmarshal(raw, &arguments); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+
+			return fn(ctx, arguments)
+		},
+	}
+}
+
+type toolStep struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+	Answer    json.RawMessage `json:"answer"`
+}
+
+func toolStepSchema(tools []Tool, answer *Schema) *Schema {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"tool":      {Type: "string", Enum: names},
+			"arguments": {Type: "object"},
+			"answer":    answer,
+		},
+	}
+}
+
+func toolInstructions(tools []Tool, answer *Schema) string {
+	var instructions strings.Builder
+	instructions.WriteString("You can call the following tools to gather information before answering:\n")
+	for _, tool := range tools {
+		fmt.Fprintf(&instructions, "\n- %s: %s\n  Arguments JSON Schema: %s\n", tool.Name, tool.Description, tool.Parameters)
+	}
+	fmt.Fprintf(&instructions, "\nEach reply must be a single JSON object. To call a tool, reply with {\"tool\": \"<name>\", \"arguments\": {...}} and wait for the result. Call one tool at a time. When you have enough information, reply with {\"answer\": ...} where the answer conforms to this JSON Schema:\n%s", answer)
+
+	return instructions.String()
+}
+
+// RunTools runs the model→tool→model loop until the model gives an answer,
+// which is decoded into result. Every model reply counts towards maxSteps,
+// including malformed ones that are sent back for correction. Calls are
+// recorded with the number of tool results the model had seen as their step,
+// and corrections within a step as retries.
+func (client *Client) RunTools(ctx context.Context, result any, chain Chain, messages []Message, tools []Tool, maxSteps int, options Options) error {
+	answerSchema := SchemaFor(result)
+	stepSchema := toolStepSchema(tools, answerSchema)
+	messages = withSystem(messages, toolInstructions(tools, answerSchema))
+
+	toolsByName := make(map[string]Tool, len(tools))
+	for _, tool := range tools {
+		toolsByName[tool.Name] = tool
+	}
+
+	var step, retry int
+	for reply := 0; reply < maxSteps; reply++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var next toolStep
+		parse := func(response string) (string, error) {
+			next = toolStep{}
+			strategy, err := unmarshalResponse(&next, stepSchema, response)
+			if err != nil {
+				return strategy, err
+			}
+			if next.Tool == "" && next.Answer == nil {
+				return strategy, &ValidationError{[]string{"$: expected either \"tool\" or \"answer\""}}
+			}
+			return strategy, nil
+		}
+
+		replyCtx := ctx
+		if reply > 0 {
+			replyCtx = WithoutCache(ctx)
+		}
+
+		response, err := client.chat(replyCtx, chain, messages, options, step, retry, parse)
+		var validationErr *ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			messages = append(messages, AssistantMessage(*response), correctionMessage(validationErr.Errors))
+			retry++
+			continue
+		case err != nil && response != nil:
+			messages = append(messages, AssistantMessage(*response), UserMessage("That reply was not a valid JSON object: "+err.Error()))
+			retry++
+			continue
+		case err != nil:
+			return err
+		}
+
+		messages = append(messages, AssistantMessage(*response))
+
+		if next.Tool == "" {
+			return json.Unmarshal(next.Answer, result)
+		}
+
+		messages = append(messages, UserMessage(client.callTool(ctx, toolsByName[next.Tool], next.Arguments)))
+		step++
+		retry = 0
+	}
+
+	return ErrTooManySteps
+}
+
+func (client *Client) callTool(ctx context.Context, tool Tool, arguments json.RawMessage) string {
+	if arguments == nil {
+		arguments = json.RawMessage("{}")
+	}
+
+	output, err := tool.call(ctx, arguments)
+	if err != nil {
+		return fmt.Sprintf("Tool %s failed: %s", tool.Name, err)
+	}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		return fmt.Sprintf("Tool %s failed: %s", tool.Name, err)
+	}
+
+	return fmt.Sprintf("Result of %s: %s", tool.Name, data)
+}
@@ -0,0 +1,194 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrBudgetExceeded = errors.New("monthly llm budget exceeded")
+
+// ErrUnpricedModel is returned for calls that count towards a budget when the
+// model has no price, as their cost would otherwise go unnoticed.
+var ErrUnpricedModel = errors.New("model has no price to check the llm budget against")
+
+// Prices are in US dollars per million tokens.
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+var defaultModelPrices = map[Model]ModelPrice{
+	ModelGpt4:         {10, 30},
+	ModelGpt3p5:       {0.5, 1.5},
+	ModelClaudeHaiku:  {0.25, 1.25},
+	ModelClaudeSonnet: {3, 15},
+}
+
+func (price ModelPrice) Cost(usage Usage) float64 {
+	return (float64(usage.InputTokens)*price.Input + float64(usage.OutputTokens)*price.Output) / 1e6
+}
+
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+type featureKey struct{}
+type userKey struct{}
+
+func WithFeature(ctx context.Context, feature string) context.Context {
+	return context.WithValue(ctx, featureKey{}, feature)
+}
+
+func FeatureFrom(ctx context.Context) string {
+	feature, _ := ctx.Value(featureKey{}).(string)
+	return feature
+}
+
+func WithUser(ctx context.Context, userId string) context.Context {
+	return context.WithValue(ctx, userKey{}, userId)
+}
+
+func UserFrom(ctx context.Context) string {
+	userId, _ := ctx.Value(userKey{}).(string)
+	return userId
+}
+
+type UsageRecord struct {
+	UserId       string
+	Feature      string
+	Model        Model
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+	Latency      time.Duration
+}
+
+type UsageStore interface {
+	RecordUsage(record UsageRecord) error
+	GetUserMonthlyCost(userId string) (float64, error)
+}
+
+const (
+	BudgetRefuse    = "refuse"
+	BudgetDowngrade = "downgrade"
+)
+
+type UsageProvider struct {
+	provider      Provider
+	store         UsageStore
+	monthlyBudget float64
+	budgetAction  string
+	fallbackModel Model
+	prices        map[Model]ModelPrice
+}
+
+func NewUsageProvider(provider Provider, store UsageStore, monthlyBudget float64, budgetAction string, prices map[Model]ModelPrice) *UsageProvider {
+	if budgetAction == "" {
+		budgetAction = BudgetDowngrade
+	}
+
+	merged := map[Model]ModelPrice{}
+	for model, price := range defaultModelPrices {
+		merged[model] = price
+	}
+	for model, price := range prices {
+		merged[model] = price
+	}
+
+	return &UsageProvider{provider, store, monthlyBudget, budgetAction, ModelClaudeHaiku, merged}
+}
+
+// LLM_MODEL_PRICES overrides the per-model default prices as a comma separated
+// list of model=input/output entries, in US dollars per million tokens. Models
+// served locally can be priced at 0/0.
+func NewUsageProviderFromEnv(provider Provider, store UsageStore) (*UsageProvider, error) {
+	monthlyBudget, _ := strconv.ParseFloat(os.Getenv("LLM_USER_MONTHLY_BUDGET"), 64)
+	prices, err := parseModelPrices(os.Getenv("LLM_MODEL_PRICES"))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewUsageProvider(provider, store, monthlyBudget, os.Getenv("LLM_BUDGET_ACTION"), prices), nil
+}
+
+func parseModelPrices(value string) (map[Model]ModelPrice, error) {
+	prices := map[Model]ModelPrice{}
+	if value == "" {
+		return prices, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		model, price, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid price entry %q", entry)
+		}
+
+		input, output, ok := strings.Cut(price, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid price entry %q", entry)
+		}
+
+		inputPrice, err := strconv.ParseFloat(input, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price entry %q: %w", entry, err)
+		}
+
+		outputPrice, err := strconv.ParseFloat(output, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price entry %q: %w", entry, err)
+		}
+
+		prices[Model(model)] = ModelPrice{inputPrice, outputPrice}
+	}
+
+	return prices, nil
+}
+
+func (provider *UsageProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	userId := UserFrom(ctx)
+	budgeted := provider.monthlyBudget > 0 && userId != ""
+
+	if budgeted {
+		spent, err := provider.store.GetUserMonthlyCost(userId)
+		if err != nil {
+			log.Println("Error reading llm usage", err)
+		} else if spent >= provider.monthlyBudget {
+			if provider.budgetAction != BudgetDowngrade {
+				return nil, ErrBudgetExceeded
+			}
+			request.Model = provider.fallbackModel
+		}
+	}
+
+	price, priced := provider.prices[request.Model]
+	if budgeted && !priced {
+		return nil, fmt.Errorf("%w: %s", ErrUnpricedModel, request.Model)
+	}
+
+	start := time.Now()
+	response, err := provider.provider.Complete(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = provider.store.RecordUsage(UsageRecord{
+		UserId:       userId,
+		Feature:      FeatureFrom(ctx),
+		Model:        request.Model,
+		InputTokens:  response.Usage.InputTokens,
+		OutputTokens: response.Usage.OutputTokens,
+		Cost:         price.Cost(response.Usage),
+		Latency:      time.Since(start),
+	})
+	if err != nil {
+		log.Println("Error recording llm usage", err)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type memoryUsage []UsageRecord
+
+func (usage *memoryUsage) RecordUsage(record UsageRecord) error {
+	*usage = append(*usage, record)
+	return nil
+}
+
+func (usage *memoryUsage) GetUserMonthlyCost(userId string) (float64, error) {
+	var cost float64
+	for _, record := range *usage {
+		if record.UserId == userId {
+			cost += record.Cost
+		}
+	}
+	return cost, nil
+}
+
+type usageProvider struct {
+	usage Usage
+}
+
+func (provider *usageProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	return &Response{Usage: provider.usage}, nil
+}
+
+func TestParseModelPrices(t *testing.T) {
+	prices, err := parseModelPrices("local-llama=0/0, gpt3-5=1/2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prices["local-llama"] != (ModelPrice{0, 0}) || prices[ModelGpt3p5] != (ModelPrice{1, 2}) {
+		t.Fatalf("got %v", prices)
+	}
+
+	for _, value := range []string{"gpt3-5", "gpt3-5=1", "gpt3-5=a/2", "gpt3-5=1/b"} {
+		if _, err := parseModelPrices(value); err == nil {
+			t.Errorf("parseModelPrices(%q) did not fail", value)
+		}
+	}
+}
+
+func TestUsageProviderRejectsUnpricedModelsUnderBudget(t *testing.T) {
+	usage := memoryUsage{}
+	inner := &cannedProvider{responses: []string{"hi"}}
+	provider := NewUsageProvider(inner, &usage, 1, BudgetRefuse, map[Model]ModelPrice{"local-llama": {0, 0}})
+	ctx := WithUser(context.Background(), "user")
+
+	_, err := provider.Complete(ctx, Request{Model: "unknown-model"})
+	if !errors.Is(err, ErrUnpricedModel) {
+		t.Fatalf("got %v, want ErrUnpricedModel", err)
+	}
+	if inner.calls != 0 {
+		t.Fatal("unpriced model was called")
+	}
+
+	if _, err := provider.Complete(ctx, Request{Model: "local-llama"}); err != nil {
+		t.Fatalf("priced local model: %v", err)
+	}
+	if _, err := provider.Complete(context.Background(), Request{Model: "unknown-model"}); err != nil {
+		t.Fatalf("call without a user: %v", err)
+	}
+	if len(usage) != 2 {
+		t.Fatalf("recorded %d calls, want 2", len(usage))
+	}
+}
+
+func TestUsageProviderChargesConfiguredPrices(t *testing.T) {
+	usage := memoryUsage{}
+	inner := &usageProvider{Usage{InputTokens: 1e6, OutputTokens: 2e6}}
+	provider := NewUsageProvider(inner, &usage, 0, "", map[Model]ModelPrice{ModelGpt3p5: {1, 2}})
+
+	if _, err := provider.Complete(context.Background(), Request{Model: ModelGpt3p5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(usage) != 1 || usage[0].Cost != 5 {
+		t.Fatalf("recorded %v, want a cost of 5", usage)
+	}
+}
@@ -0,0 +1,381 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+var (
+	errUnattributedFrame = errors.New("gateway answered without a request_id while several requests were pending")
+	errAbandonedConn     = errors.New("connection dropped after a request was abandoned")
+)
+
+const (
+	DefaultWebsocketPoolSize    = 4
+	DefaultWebsocketMaxInFlight = 8
+
+	websocketMinBackoff = 100 * time.Millisecond
+	websocketMaxBackoff = 10 * time.Second
+)
+
+type WebsocketProvider struct {
+	conns    []*websocketConn
+	slots    chan struct{}
+	selectMu sync.Mutex
+}
+
+func NewWebsocketProvider(uri string, poolSize, maxInFlight int) *WebsocketProvider {
+	if poolSize <= 0 {
+		poolSize = DefaultWebsocketPoolSize
+	}
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultWebsocketMaxInFlight
+	}
+
+	conns := make([]*websocketConn, poolSize)
+	for i := range conns {
+		conns[i] = &websocketConn{uri: uri, pending: map[string]chan websocketFrame{}, serial: make(chan struct{}, 1)}
+	}
+
+	return &WebsocketProvider{conns: conns, slots: make(chan struct{}, poolSize*maxInFlight)}
+}
+
+type promptData struct {
+	Action      string   `json:"action"`
+	RequestId   string   `json:"request_id"`
+	Model       string   `json:"model"`
+	Prompt      string   `json:"prompt"`
+	System      string   `json:"system"`
+	Temperature *float64 `json:"temperature"`
+	Stop        []string `json:"stop,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Json        bool     `json:"json,omitempty"`
+}
+
+type websocketFrame struct {
+	RequestId    string  `json:"request_id"`
+	Result       *string `json:"result"`
+	Error        string  `json:"error"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	err          error
+}
+
+func (provider *WebsocketProvider) Complete(ctx context.Context, request Request) (*Response, error) {
+	select {
+	case provider.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-provider.slots }()
+
+	requestData := promptData{
+		Action:      "runModel",
+		RequestId:   uuid.New().String(),
+		Model:       request.Model.String(),
+		Prompt:      request.Transcript(),
+		System:      request.System(),
+		Temperature: request.Temperature,
+		Stop:        request.Stop,
+		MaxTokens:   request.MaxTokens,
+		Json:        request.Json,
+	}
+	message, err := json.Marshal(requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := provider.reserve()
+	defer conn.release()
+
+	unserialize, err := conn.serialize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unserialize()
+
+	frames, err := conn.send(ctx, requestData.RequestId, message)
+	if err != nil {
+		return nil, err
+	}
+
+	var frame websocketFrame
+	select {
+	case frame = <-frames:
+	case <-ctx.Done():
+		conn.abandon(requestData.RequestId)
+		return nil, ctx.Err()
+	}
+	if frame.err != nil {
+		return nil, frame.err
+	}
+	if frame.Error != "" {
+		return nil, fmt.Errorf("response error: %s", frame.Error)
+	}
+
+	var result string
+	if frame.Result != nil {
+		result = *frame.Result
+	}
+
+	usage := Usage{InputTokens: frame.InputTokens, OutputTokens: frame.OutputTokens}
+	if usage.InputTokens == 0 && usage.OutputTokens == 0 {
+		usage = Usage{
+			InputTokens:  estimateTokens(requestData.System) + estimateTokens(requestData.Prompt),
+			OutputTokens: estimateTokens(result),
+		}
+	}
+
+	// The gateway does not promise to honour stop sequences.
+	return &Response{Text: truncateAtStop(result, request.Stop), Usage: usage}, nil
+}
+
+func (provider *WebsocketProvider) reserve() *websocketConn {
+	provider.selectMu.Lock()
+	defer provider.selectMu.Unlock()
+
+	best := provider.conns[0]
+	bestLoad := best.load()
+	for _, conn := range provider.conns[1:] {
+		if load := conn.load(); load < bestLoad {
+			best, bestLoad = conn, load
+		}
+	}
+
+	best.mu.Lock()
+	best.inFlight++
+	best.mu.Unlock()
+
+	return best
+}
+
+type websocketConn struct {
+	uri string
+
+	dialMu  sync.Mutex
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	conn     *websocket.Conn
+	pending  map[string]chan websocketFrame
+	inFlight int
+	failures int
+	retryAt  time.Time
+
+	// Legacy gateways answer without echoing request_id, so until the
+	// gateway has echoed one, requests on a connection are sent one at a time
+	// to keep every answer attributable.
+	echoesIds bool
+	serial    chan struct{}
+}
+
+func (wc *websocketConn) load() int {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	return wc.inFlight
+}
+
+func (wc *websocketConn) release() {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	wc.inFlight--
+}
+
+func (wc *websocketConn) serialize(ctx context.Context) (func(), error) {
+	wc.mu.Lock()
+	echoesIds := wc.echoesIds
+	wc.mu.Unlock()
+
+	if echoesIds {
+		return func() {}, nil
+	}
+
+	select {
+	case wc.serial <- struct{}{}:
+		return func() { <-wc.serial }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (wc *websocketConn) send(ctx context.Context, id string, message []byte) (chan websocketFrame, error) {
+	conn, err := wc.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := make(chan websocketFrame, 1)
+	wc.mu.Lock()
+	wc.pending[id] = frames
+	wc.mu.Unlock()
+
+	wc.writeMu.Lock()
+	err = conn.WriteMessage(websocket.TextMessage, message)
+	wc.writeMu.Unlock()
+	if err != nil {
+		wc.fail(conn, err)
+		return nil, err
+	}
+
+	return frames, nil
+}
+
+// abandon forgets a request the caller stopped waiting for. A gateway that
+// does not echo ids would still answer it and the answer would go to the next
+// request, so the connection is dropped instead.
+func (wc *websocketConn) abandon(id string) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	delete(wc.pending, id)
+	if wc.echoesIds || wc.conn == nil {
+		return
+	}
+
+	wc.conn.Close()
+	wc.conn = nil
+	for id, frames := range wc.pending {
+		frames <- websocketFrame{err: errAbandonedConn}
+		delete(wc.pending, id)
+	}
+}
+
+func (wc *websocketConn) connect(ctx context.Context) (*websocket.Conn, error) {
+	wc.dialMu.Lock()
+	defer wc.dialMu.Unlock()
+
+	wc.mu.Lock()
+	conn, retryAt := wc.conn, wc.retryAt
+	wc.mu.Unlock()
+
+	if conn != nil {
+		return conn, nil
+	}
+
+	if wait := time.Until(retryAt); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wc.uri, nil)
+
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	if err != nil {
+		if ctx.Err() == nil {
+			wc.failures++
+			wc.retryAt = time.Now().Add(backoff(wc.failures))
+		}
+		return nil, err
+	}
+
+	wc.conn = conn
+	wc.failures = 0
+	go wc.readLoop(conn)
+
+	return conn, nil
+}
+
+func (wc *websocketConn) readLoop(conn *websocket.Conn) {
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			wc.fail(conn, fmt.Errorf("read message: %w", err))
+			return
+		}
+
+		frame := websocketFrame{}
+		if err := json.Unmarshal(message, &frame); err != nil {
+			log.Println("Error unmarshaling websocket frame", err)
+			continue
+		}
+
+		if frame.Result == nil && frame.Error == "" {
+			continue
+		}
+
+		wc.mu.Lock()
+		if frame.RequestId == "" {
+			wc.routeAnonymous(frame)
+			wc.mu.Unlock()
+			continue
+		}
+
+		frames, ok := wc.pending[frame.RequestId]
+		if ok {
+			delete(wc.pending, frame.RequestId)
+			wc.echoesIds = true
+		}
+		wc.mu.Unlock()
+
+		if !ok {
+			log.Println("Dropping websocket frame for unknown request", frame.RequestId)
+			continue
+		}
+
+		frames <- frame
+	}
+}
+
+// routeAnonymous hands a frame without a request_id to the only pending
+// request. When several are pending the answer cannot be attributed, so they
+// all fail and later requests are sent one at a time. Callers hold wc.mu.
+func (wc *websocketConn) routeAnonymous(frame websocketFrame) {
+	if len(wc.pending) == 1 {
+		for id, frames := range wc.pending {
+			delete(wc.pending, id)
+			frames <- frame
+		}
+		return
+	}
+
+	wc.echoesIds = false
+	for id, frames := range wc.pending {
+		frames <- websocketFrame{err: errUnattributedFrame}
+		delete(wc.pending, id)
+	}
+}
+
+func (wc *websocketConn) fail(conn *websocket.Conn, err error) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	if wc.conn != conn {
+		return
+	}
+
+	conn.Close()
+	wc.conn = nil
+	wc.failures++
+	wc.retryAt = time.Now().Add(backoff(wc.failures))
+
+	for id, frames := range wc.pending {
+		frames <- websocketFrame{err: err}
+		delete(wc.pending, id)
+	}
+}
+
+func backoff(failures int) time.Duration {
+	delay := websocketMinBackoff << (failures - 1)
+	if delay <= 0 || delay > websocketMaxBackoff {
+		return websocketMaxBackoff
+	}
+
+	return delay
+}
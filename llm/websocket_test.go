@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// gatewayServer answers each request with result(prompt). With echo set it
+// echoes request_id and answers requests concurrently; without it, it answers
+// them in order like the legacy gateway.
+func gatewayServer(t *testing.T, echo bool, result func(prompt string) string) string {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var writeMu sync.Mutex
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var request promptData
+			json.Unmarshal(message, &request)
+
+			reply := func() {
+				time.Sleep(10 * time.Millisecond)
+				frame := map[string]any{"result": result(request.Prompt)}
+				if echo {
+					frame["request_id"] = request.RequestId
+				}
+
+				writeMu.Lock()
+				defer writeMu.Unlock()
+				conn.WriteJSON(map[string]any{"status": "working"})
+				conn.WriteJSON(frame)
+			}
+			if echo {
+				go reply()
+			} else {
+				reply()
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func completeConcurrently(t *testing.T, provider Provider, count int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prompt := fmt.Sprintf("prompt %d", i)
+			response, err := provider.Complete(ctx, Request{Model: "test-model", Messages: []Message{UserMessage(prompt)}})
+			if err != nil {
+				t.Errorf("request %d: %v", i, err)
+				return
+			}
+			if !strings.Contains(response.Text, prompt) {
+				t.Errorf("request %d got answer %q", i, response.Text)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWebsocketLegacyGatewayConcurrent(t *testing.T) {
+	uri := gatewayServer(t, false, func(prompt string) string { return prompt })
+	completeConcurrently(t, NewWebsocketProvider(uri, 1, 8), 6)
+}
+
+func TestWebsocketEchoingGatewayConcurrent(t *testing.T) {
+	uri := gatewayServer(t, true, func(prompt string) string { return prompt })
+	completeConcurrently(t, NewWebsocketProvider(uri, 2, 8), 12)
+}
+
+func TestWebsocketEmptyResult(t *testing.T) {
+	uri := gatewayServer(t, true, func(string) string { return "" })
+	provider := NewWebsocketProvider(uri, 1, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	response, err := provider.Complete(ctx, Request{Model: "test-model", Messages: []Message{UserMessage("hi")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Text != "" {
+		t.Fatalf("got %q, want an empty answer", response.Text)
+	}
+}
+
+func TestWebsocketLegacyGatewayAbandonedRequest(t *testing.T) {
+	uri := gatewayServer(t, false, func(prompt string) string {
+		if strings.Contains(prompt, "slow") {
+			time.Sleep(200 * time.Millisecond)
+		}
+		return prompt
+	})
+	provider := NewWebsocketProvider(uri, 1, 8)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := provider.Complete(ctx, Request{Model: "test-model", Messages: []Message{UserMessage("slow prompt")}}); err == nil {
+		t.Fatal("expected the slow request to time out")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	response, err := provider.Complete(ctx, Request{Model: "test-model", Messages: []Message{UserMessage("fast prompt")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(response.Text, "fast prompt") {
+		t.Fatalf("got the abandoned request's answer %q", response.Text)
+	}
+
+	// Wait out the abandoned reply to make sure it never surfaces.
+	time.Sleep(250 * time.Millisecond)
+	response, err = provider.Complete(ctx, Request{Model: "test-model", Messages: []Message{UserMessage("fast again")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(response.Text, "fast again") {
+		t.Fatalf("got %q", response.Text)
+	}
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
+	"expvar"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/nvdaz/find-a-friend-api/db"
 	"github.com/nvdaz/find-a-friend-api/handler"
+	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/llm/match"
 	"github.com/nvdaz/find-a-friend-api/service"
+	"github.com/nvdaz/find-a-friend-api/vector"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -20,13 +27,73 @@ func main() {
 	}
 	defer database.Close()
 
+	provider, err := llm.NewProviderFromEnv()
+	if err != nil {
+		fmt.Println("Error configuring llm provider:", err)
+		os.Exit(1)
+	}
+	provider, err = llm.NewSchedulingProviderFromEnv(provider)
+	if err != nil {
+		fmt.Println("Error configuring llm scheduler:", err)
+		os.Exit(1)
+	}
+	usageService := service.NewUsageService(db.NewUsageStore(database))
+	provider, err = llm.NewUsageProviderFromEnv(provider, &usageService)
+	if err != nil {
+		fmt.Println("Error configuring llm usage:", err)
+		os.Exit(1)
+	}
+	cacheStore := db.NewCacheStore(database)
+	provider = llm.NewCachingProviderFromEnv(provider, &cacheStore)
+	embedder, err := llm.NewEmbedderFromEnv()
+	if err != nil {
+		fmt.Println("Error configuring llm embedder:", err)
+		os.Exit(1)
+	}
+	callService := service.NewCallService(db.NewCallStore(database))
+	llmClient := llm.NewClient(provider, embedder, &callService)
+
+	embeddingService := service.NewEmbeddingService(db.NewEmbeddingStore(database))
+	vectorIndex := vector.NewIndex(&embeddingService, llmClient)
+	if err := vectorIndex.Load(); err != nil {
+		fmt.Println("Error loading vector index:", err)
+		os.Exit(1)
+	}
+
 	userStore := db.NewUserStore(database)
 	messageStore := db.NewMessagesStore(database)
 	matchStore := db.NewMatchStore(database)
-	userService := service.NewUserService(userStore, messageStore)
-	matchService := service.NewMatchService(userService, matchStore)
-	messageService := service.NewMessagesService(messageStore, userService)
-	h := handler.NewHandler(userService, matchService, messageService)
+	userService := service.NewUserService(userStore, messageStore, llmClient, vectorIndex)
+	go func() {
+		if err := userService.IndexProfiles(context.Background()); err != nil {
+			fmt.Println("Error indexing profiles:", err)
+		}
+	}()
+	feedbackService := service.NewFeedbackService(db.NewFeedbackStore(database), matchStore)
+	matchService := service.NewMatchService(userService, matchStore, db.NewMatchRunStore(database), feedbackService, llmClient, match.NewRetrievalFromEnv(vectorIndex))
+	go func() {
+		for range time.Tick(time.Hour) {
+			if _, err := matchService.ExpireMatches(); err != nil {
+				fmt.Println("Error expiring matches:", err)
+			}
+		}
+	}()
+	if interval, perUser := service.BatchMatchScheduleFromEnv(); interval > 0 {
+		go func() {
+			for range time.Tick(interval) {
+				run, err := matchService.RunBatchMatch(context.Background(), perUser, false)
+				if err != nil {
+					fmt.Println("Error running batch match:", err)
+					continue
+				}
+				fmt.Printf("Batch match proposed %d pairings for %d users\n", len(run.Pairings), run.Users)
+			}
+		}()
+	}
+	blockService := service.NewBlockService(db.NewBlockStore(database))
+	messageService := service.NewMessagesService(messageStore, blockService, userService)
+	groupService := service.NewGroupService(userService, db.NewGroupStore(database), llmClient)
+	h := handler.NewHandler(userService, matchService, messageService, usageService, callService, blockService, groupService, feedbackService, service.NewSessionServiceFromEnv())
 
 	e := echo.New()
 
@@ -35,9 +102,17 @@ func main() {
 	e.GET("/user/:id", h.GetUser)
 	e.POST("/user/:id", h.UpdateUser)
 	e.GET("/user/:id/matches", h.GetUserMatches)
+	e.POST("/user/:id/blocks", h.BlockUser)
+	e.GET("/user/:id/groups", h.GetUserGroups)
+	e.POST("/user/:id/groups", h.FormUserGroup)
 	e.POST("/user/:id/matches", h.GenerateUserMatch)
-	e.GET("/users", h.GetAllUsers)
+	e.GET("/users", h.GetAllUsers, middleware.KeyAuth(h.Authenticate))
 	e.GET("/match/:id", h.GetMatch)
+	e.POST("/match/:id/accept", h.AcceptMatch)
+	e.POST("/match/:id/decline", h.DeclineMatch)
+	e.DELETE("/match/:id", h.Unmatch)
+	e.POST("/match/:id/feedback", h.RateMatch)
+	e.GET("/group/:id", h.GetGroup)
 	e.POST("/messages", h.GetMessages)
 	e.POST("/messages/create", h.CreateMessage)
 	e.POST("/messages/poll", h.PollMessages)
@@ -45,6 +120,18 @@ func main() {
 	e.POST("/login", h.LoginUser)
 	e.POST("/set-icon", h.UpdateUserIcon)
 
+	admin := e.Group("/admin", middleware.KeyAuth(func(key string, c echo.Context) (bool, error) {
+		adminToken := os.Getenv("ADMIN_TOKEN")
+		return adminToken != "" && subtle.ConstantTimeCompare([]byte(key), []byte(adminToken)) == 1, nil
+	}))
+	admin.GET("/usage", h.GetUsage)
+	admin.GET("/calls", h.GetCalls)
+	admin.GET("/match/:id/run", h.GetMatchRun)
+	admin.POST("/batch-match", h.RunBatchMatch)
+	admin.POST("/batch-groups", h.RunGroupBatch)
+	admin.GET("/feedback", h.GetFeedbackReport)
+	admin.GET("/metrics", echo.WrapHandler(expvar.Handler()))
+
 	fmt.Println("Starting server...")
 
 	port := os.Getenv("PORT")
@@ -0,0 +1,65 @@
+-- Brings a database created from an older db.sql up to date. Run the
+-- statements added since it was created, oldest first, then db.sql to create
+-- any new tables and indexes.
+
+-- Prompt versions.
+ALTER TABLE `users` ADD COLUMN `prompt_versions` JSONB;
+ALTER TABLE `matches` ADD COLUMN `prompt_versions` JSONB;
+
+-- Correlation ids.
+ALTER TABLE `users` ADD COLUMN `profile_correlation_id` VARCHAR(36);
+ALTER TABLE `matches` ADD COLUMN `correlation_id` VARCHAR(36);
+
+-- Parse strategies, for databases that already have llm_calls.
+ALTER TABLE `llm_calls` ADD COLUMN `parse_strategy` TEXT;
+
+-- Compatibility scores.
+ALTER TABLE `matches` ADD COLUMN `compatibility` JSONB;
+
+-- Match lifecycle.
+ALTER TABLE `matches` ADD COLUMN `status` TEXT NOT NULL DEFAULT 'pending';
+ALTER TABLE `matches` ADD COLUMN `expires_at` DATETIME;
+-- Matches made before the lifecycle existed were shown to both users
+-- straight away. Every match made since has an expiry.
+UPDATE `matches` SET `status` = 'active' WHERE `expires_at` IS NULL AND `status` = 'pending';
+
+-- Archived conversations.
+ALTER TABLE `messages` ADD COLUMN `archived_at` DATETIME;
+
+-- Structured explanations.
+ALTER TABLE `matches` ADD COLUMN `explanation` JSONB;
+
+-- Match run outcomes, for databases that already have match_runs.
+ALTER TABLE `match_runs` ADD COLUMN `outcome` TEXT NOT NULL DEFAULT 'matched';
+ALTER TABLE `match_runs` ADD COLUMN `error` TEXT;
+-- Failed runs have no match, and dropping NOT NULL from match_id means
+-- copying the table.
+CREATE TABLE `match_runs_new` (
+    `id` VARCHAR(36) PRIMARY KEY,
+    `match_id` VARCHAR(36),
+    `user_id` VARCHAR(36) NOT NULL,
+    `correlation_id` VARCHAR(36) NOT NULL,
+    `pool_size` INTEGER NOT NULL,
+    `weights` JSONB NOT NULL,
+    `candidates` JSONB NOT NULL,
+    `chosen_id` VARCHAR(36) NOT NULL,
+    `reasoning` TEXT NOT NULL,
+    `fallback` BOOLEAN NOT NULL,
+    `models` JSONB NOT NULL,
+    `timings` JSONB NOT NULL,
+    `prompt_versions` JSONB NOT NULL,
+    `outcome` TEXT NOT NULL,
+    `error` TEXT,
+    `created_at` DATETIME NOT NULL,
+    CONSTRAINT `fk_user_id` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+INSERT INTO `match_runs_new` (`id`, `match_id`, `user_id`, `correlation_id`, `pool_size`, `weights`, `candidates`, `chosen_id`,
+                              `reasoning`, `fallback`, `models`, `timings`, `prompt_versions`, `outcome`, `error`, `created_at`)
+SELECT `id`, `match_id`, `user_id`, `correlation_id`, `pool_size`, `weights`, `candidates`, `chosen_id`,
+       `reasoning`, `fallback`, `models`, `timings`, `prompt_versions`, `outcome`, `error`, `created_at`
+FROM `match_runs`;
+DROP TABLE `match_runs`;
+ALTER TABLE `match_runs_new` RENAME TO `match_runs`;
+
+-- Tool call steps, for databases that already have llm_calls.
+ALTER TABLE `llm_calls` ADD COLUMN `step` INTEGER NOT NULL DEFAULT 0;
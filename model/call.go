@@ -0,0 +1,19 @@
+package model
+
+type LlmCall struct {
+	Id            string  `json:"id"`
+	CorrelationId string  `json:"correlation_id"`
+	UserId        string  `json:"user_id"`
+	Caller        string  `json:"caller"`
+	Model         string  `json:"model"`
+	System        string  `json:"system"`
+	Prompt        string  `json:"prompt"`
+	Response      *string `json:"response"`
+	Outcome       string  `json:"outcome"`
+	Strategy      *string `json:"parse_strategy"`
+	Error         *string `json:"error"`
+	Step          int     `json:"step"`
+	Retry         int     `json:"retry"`
+	LatencyMs     int64   `json:"latency_ms"`
+	CreatedAt     string  `json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package model
+
+const (
+	FeedbackTagGreatChat     = "great chat"
+	FeedbackTagNoReply       = "no reply"
+	FeedbackTagMetUp         = "met up"
+	FeedbackTagNotAFit       = "not a fit"
+	FeedbackTagInappropriate = "inappropriate"
+)
+
+var FeedbackTags = []string{
+	FeedbackTagGreatChat,
+	FeedbackTagNoReply,
+	FeedbackTagMetUp,
+	FeedbackTagNotAFit,
+	FeedbackTagInappropriate,
+}
+
+type MatchFeedback struct {
+	Rating int      `json:"rating"`
+	Tags   []string `json:"tags"`
+}
+
+type FeatureWeights struct {
+	Interests   float64 `json:"interests"`
+	Topics      float64 `json:"topics"`
+	Personality float64 `json:"personality"`
+	Languages   float64 `json:"languages"`
+	Values      float64 `json:"values"`
+	Goals       float64 `json:"goals"`
+}
+
+type FeedbackReport struct {
+	Ratings        map[string]int `json:"ratings"`
+	Rated          int            `json:"rated"`
+	AverageRating  float64        `json:"average_rating"`
+	Tags           map[string]int `json:"tags"`
+	Matches        int            `json:"matches"`
+	ReplyRate      float64        `json:"reply_rate"`
+	AverageOutcome float64        `json:"average_outcome"`
+	DefaultWeights FeatureWeights `json:"default_weights"`
+	LearnedWeights FeatureWeights `json:"learned_weights"`
+}
@@ -0,0 +1,25 @@
+package model
+
+type Group struct {
+	Id             string            `json:"id,omitempty"`
+	Name           string            `json:"name,omitempty"`
+	Intro          string            `json:"intro,omitempty"`
+	Themes         []string          `json:"themes"`
+	Affinity       float64           `json:"affinity,omitempty"`
+	PromptVersions map[string]string `json:"prompt_versions,omitempty"`
+	Members        []GroupMember     `json:"members"`
+	CreatedAt      string            `json:"created_at,omitempty"`
+	Error          *string           `json:"error,omitempty"`
+}
+
+type GroupMember struct {
+	UserId      string `json:"user_id"`
+	Name        string `json:"name"`
+	Explanation string `json:"explanation,omitempty"`
+}
+
+type GroupRun struct {
+	DryRun bool    `json:"dry_run"`
+	Users  int     `json:"users"`
+	Groups []Group `json:"groups"`
+}
@@ -1,8 +1,100 @@
 package model
 
+type MatchStatus string
+
+// Each user answers their own direction of a match. The match becomes active
+// once both have accepted and closed for the other user when one declines.
+const (
+	MatchStatusPending  MatchStatus = "pending"
+	MatchStatusAccepted MatchStatus = "accepted"
+	MatchStatusDeclined MatchStatus = "declined"
+	MatchStatusActive   MatchStatus = "active"
+	MatchStatusClosed   MatchStatus = "closed"
+	MatchStatusExpired  MatchStatus = "expired"
+)
+
 type Match struct {
-	Id      string `json:"id"`
-	UserId  string `json:"user_id"`
-	OtherId string `json:"other_id"`
-	Reason  string `json:"reason"`
+	Id             string               `json:"id"`
+	UserId         string               `json:"user_id"`
+	OtherId        string               `json:"other_id"`
+	Reason         string               `json:"reason"`
+	PromptVersions map[string]string    `json:"prompt_versions,omitempty"`
+	Compatibility  *CompatibilityScores `json:"compatibility,omitempty"`
+	Explanation    *MatchExplanation    `json:"explanation,omitempty"`
+	Status         MatchStatus          `json:"status"`
+	ExpiresAt      *string              `json:"expires_at,omitempty"`
+}
+
+// MatchExplanation is what a match card shows one user about the other.
+type MatchExplanation struct {
+	Reason               string     `json:"reason"`
+	SharedInterests      []Interest `json:"shared_interests"`
+	ComplementaryTraits  []string   `json:"complementary_traits"`
+	ConversationStarters []string   `json:"conversation_starters"`
+}
+
+type CompatibilityScores struct {
+	Interests   float64 `json:"interests"`
+	Topics      float64 `json:"topics"`
+	Personality float64 `json:"personality"`
+	Languages   float64 `json:"languages"`
+	Values      float64 `json:"values"`
+	Goals       float64 `json:"goals"`
+	Overall     float64 `json:"overall"`
+}
+
+type BatchPairing struct {
+	UserId        string              `json:"user_id"`
+	OtherId       string              `json:"other_id"`
+	Compatibility CompatibilityScores `json:"compatibility"`
+	MatchId       *string             `json:"match_id,omitempty"`
+	Error         *string             `json:"error,omitempty"`
+}
+
+type BatchRun struct {
+	DryRun   bool           `json:"dry_run"`
+	PerUser  int            `json:"per_user"`
+	Users    int            `json:"users"`
+	Pairings []BatchPairing `json:"pairings"`
+}
+
+// MatchRunCandidate is one user considered for a match and how far they got.
+type MatchRunCandidate struct {
+	UserId          string              `json:"user_id"`
+	Name            string              `json:"name"`
+	Compatibility   CompatibilityScores `json:"compatibility"`
+	Shortlisted     bool                `json:"shortlisted"`
+	ShortlistReason string              `json:"shortlist_reason,omitempty"`
+	Explanation     string              `json:"explanation,omitempty"`
+}
+
+// A match run ends in a match, finds nobody to match or fails part way, in
+// which case it keeps whatever it got through.
+type MatchRunOutcome string
+
+const (
+	MatchRunOutcomeMatched MatchRunOutcome = "matched"
+	MatchRunOutcomeNoMatch MatchRunOutcome = "no_match"
+	MatchRunOutcomeFailed  MatchRunOutcome = "failed"
+)
+
+// MatchRun records how a match was generated: who was considered, why each
+// candidate was shortlisted and how the final choice was made.
+type MatchRun struct {
+	Id             string              `json:"id"`
+	MatchId        *string             `json:"match_id,omitempty"`
+	UserId         string              `json:"user_id"`
+	CorrelationId  string              `json:"correlation_id"`
+	PoolSize       int                 `json:"pool_size"`
+	Weights        FeatureWeights      `json:"weights"`
+	Candidates     []MatchRunCandidate `json:"candidates"`
+	ChosenId       string              `json:"chosen_id"`
+	Reasoning      string              `json:"reasoning"`
+	Fallback       bool                `json:"fallback"`
+	Models         map[string][]string `json:"models"`
+	TimingsMs      map[string]int64    `json:"timings_ms"`
+	PromptVersions map[string]string   `json:"prompt_versions"`
+	Outcome        MatchRunOutcome     `json:"outcome"`
+	Error          *string             `json:"error,omitempty"`
+	CreatedAt      string              `json:"created_at,omitempty"`
 }
@@ -0,0 +1,10 @@
+package model
+
+type UsageSummary struct {
+	Key          string  `json:"key"`
+	Calls        int     `json:"calls"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+	LatencyMs    float64 `json:"latency_ms"`
+}
@@ -1,11 +1,11 @@
 package model
 
 type Personality struct {
-	Extroversion      float64 `json:"extroversion"`
-	Agreeableness     float64 `json:"agreeableness"`
-	Conscientiousness float64 `json:"conscientiousness"`
-	Neuroticism       float64 `json:"neuroticism"`
-	Openness          float64 `json:"openness"`
+	Extroversion      float64 `json:"extroversion" jsonschema:"minimum=0,maximum=5"`
+	Agreeableness     float64 `json:"agreeableness" jsonschema:"minimum=0,maximum=5"`
+	Conscientiousness float64 `json:"conscientiousness" jsonschema:"minimum=0,maximum=5"`
+	Neuroticism       float64 `json:"neuroticism" jsonschema:"minimum=0,maximum=5"`
+	Openness          float64 `json:"openness" jsonschema:"minimum=0,maximum=5"`
 }
 
 type User struct {
@@ -17,28 +17,28 @@ type User struct {
 
 type Interest struct {
 	Interest string  `json:"interest"`
-	Level    float64 `json:"level"`
+	Level    float64 `json:"level" jsonschema:"minimum=0,maximum=1"`
 	Emoji    string  `json:"emoji"`
 }
 
 type Skill struct {
 	Skill string  `json:"skill"`
-	Level float64 `json:"level"`
+	Level float64 `json:"level" jsonschema:"minimum=0,maximum=1"`
 }
 
 type Goal struct {
 	Goal       string  `json:"goal"`
-	Importance float64 `json:"importance"`
+	Importance float64 `json:"importance" jsonschema:"minimum=0,maximum=1"`
 }
 
 type CoreValue struct {
 	Value      string  `json:"value"`
-	Importance float64 `json:"importance"`
+	Importance float64 `json:"importance" jsonschema:"minimum=0,maximum=1"`
 }
 
 type MediaInterest struct {
 	MediaInterest string  `json:"media_interest"`
-	Level         float64 `json:"level"`
+	Level         float64 `json:"level" jsonschema:"minimum=0,maximum=1"`
 }
 
 type Background struct {
@@ -53,7 +53,7 @@ type Tag struct {
 
 type Topic struct {
 	Topic string  `json:"topic"`
-	Level float64 `json:"level"`
+	Level float64 `json:"level" jsonschema:"minimum=0,maximum=1"`
 	Emoji string  `json:"emoji"`
 }
 
@@ -73,29 +73,29 @@ type IntermediateProfile struct {
 }
 
 type Demographics struct {
-	AgeRange             string   `json:"age_range"`
-	Gender               string   `json:"gender"`
+	AgeRange             string   `json:"age_range" jsonschema:"enum=under 18|18-24|25-34|35-44|45-54|55-64|65+"`
+	Gender               string   `json:"gender" jsonschema:"enum=male|female|non-binary|unknown"`
 	Location             string   `json:"location"`
 	Occupation           string   `json:"occupation"`
-	HighestEducation     string   `json:"highest_education"`
-	LivingStatus         string   `json:"living_status"`
-	PoliticalAffiliation string   `json:"political_affiliation"`
+	HighestEducation     string   `json:"highest_education" jsonschema:"enum=none|high school|some college|associate degree|bachelor's degree|master's degree|doctorate"`
+	LivingStatus         string   `json:"living_status" jsonschema:"enum=alone|with partner|with family|with roommates|student housing"`
+	PoliticalAffiliation string   `json:"political_affiliation" jsonschema:"enum=progressive|liberal|moderate|conservative|libertarian|independent|apolitical"`
 	ReligiousAffiliation string   `json:"religious_affiliation"`
 	Nationality          string   `json:"nationality"`
-	SpokenLanguages      []string `json:"spoken_languages"`
-	SocialClass          string   `json:"social_class"`
+	SpokenLanguages      []string `json:"spoken_languages" jsonschema:"minItems=1"`
+	SocialClass          string   `json:"social_class" jsonschema:"enum=working class|lower middle class|middle class|upper middle class|upper class"`
 }
 
 type InterpersonalSkills struct {
-	ActiveListening float64 `json:"active_listening"`
-	Teamwork        float64 `json:"teamwork"`
-	Responsibility  float64 `json:"responsibility"`
-	Dependability   float64 `json:"dependability"`
-	Leadership      float64 `json:"leadership"`
-	Motivation      float64 `json:"motivation"`
-	Flexibility     float64 `json:"flexibility"`
-	Patience        float64 `json:"patience"`
-	Empathy         float64 `json:"empathy"`
+	ActiveListening float64 `json:"active_listening" jsonschema:"minimum=0,maximum=1"`
+	Teamwork        float64 `json:"teamwork" jsonschema:"minimum=0,maximum=1"`
+	Responsibility  float64 `json:"responsibility" jsonschema:"minimum=0,maximum=1"`
+	Dependability   float64 `json:"dependability" jsonschema:"minimum=0,maximum=1"`
+	Leadership      float64 `json:"leadership" jsonschema:"minimum=0,maximum=1"`
+	Motivation      float64 `json:"motivation" jsonschema:"minimum=0,maximum=1"`
+	Flexibility     float64 `json:"flexibility" jsonschema:"minimum=0,maximum=1"`
+	Patience        float64 `json:"patience" jsonschema:"minimum=0,maximum=1"`
+	Empathy         float64 `json:"empathy" jsonschema:"minimum=0,maximum=1"`
 }
 
 type ProfileFeatures struct {
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nvdaz/find-a-friend-api/compatibility"
+	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/llm/prompt"
+	"github.com/nvdaz/find-a-friend-api/model"
+	"golang.org/x/sync/errgroup"
+)
+
+// BatchMatchConcurrency limits how many chosen pairs are explained at once.
+const BatchMatchConcurrency = 4
+
+// BATCH_MATCH_INTERVAL (a Go duration such as 24h) enables the scheduled batch
+// matcher, giving every user BATCH_MATCH_PER_USER new matches per run.
+func BatchMatchScheduleFromEnv() (time.Duration, int) {
+	interval, _ := time.ParseDuration(os.Getenv("BATCH_MATCH_INTERVAL"))
+	perUser, _ := strconv.Atoi(os.Getenv("BATCH_MATCH_PER_USER"))
+	if perUser < 1 {
+		perUser = 1
+	}
+
+	return interval, perUser
+}
+
+// RunBatchMatch pairs up the whole user pool at once with a maximum weight
+// matching over compatibility scores. Explanations are generated only for the
+// chosen pairs, and nothing is written in a dry run.
+func (service *MatchService) RunBatchMatch(ctx context.Context, perUser int, dryRun bool) (model.BatchRun, error) {
+	users, err := service.UserService.GetAllUsers("")
+	if err != nil {
+		return model.BatchRun{}, err
+	}
+
+	unavailable, err := service.matchStore.GetUnavailablePairs()
+	if err != nil {
+		return model.BatchRun{}, err
+	}
+
+	excluded := map[[2]string]bool{}
+	for _, pair := range unavailable {
+		excluded[[2]string{pair.UserId, pair.OtherId}] = true
+		excluded[[2]string{pair.OtherId, pair.UserId}] = true
+	}
+
+	pairs := compatibility.Pairings(users, perUser, func(a, b string) bool {
+		return !excluded[[2]string{a, b}]
+	})
+
+	run := model.BatchRun{
+		DryRun:   dryRun,
+		PerUser:  perUser,
+		Users:    len(users),
+		Pairings: make([]model.BatchPairing, len(pairs)),
+	}
+	for i, pair := range pairs {
+		run.Pairings[i] = model.BatchPairing{
+			UserId:        pair.A.Id,
+			OtherId:       pair.B.Id,
+			Compatibility: pair.Scores,
+		}
+	}
+
+	if dryRun {
+		return run, nil
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(BatchMatchConcurrency)
+	for i, pair := range pairs {
+		group.Go(func() error {
+			recorder := prompt.NewRecorder()
+			correlationId := uuid.New().String()
+			pairCtx := llm.WithCorrelationId(prompt.WithRecorder(llm.WithUser(groupCtx, pair.A.Id), recorder), correlationId)
+
+			match, err := service.createMatch(pairCtx, recorder, correlationId, pair.A, pair.B)
+			if err != nil {
+				message := err.Error()
+				run.Pairings[i].Error = &message
+				return nil
+			}
+
+			run.Pairings[i].MatchId = &match.Id
+			return nil
+		})
+	}
+	group.Wait()
+
+	return run, nil
+}
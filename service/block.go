@@ -0,0 +1,29 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/nvdaz/find-a-friend-api/db"
+)
+
+var ErrSelfBlock = errors.New("users cannot block themselves")
+
+type BlockService struct {
+	blockStore db.BlockStore
+}
+
+func NewBlockService(blockStore db.BlockStore) BlockService {
+	return BlockService{blockStore}
+}
+
+func (service *BlockService) BlockUser(blockerId, blockedId string) error {
+	if blockerId == blockedId {
+		return ErrSelfBlock
+	}
+
+	return service.blockStore.CreateBlock(blockerId, blockedId)
+}
+
+func (service *BlockService) IsBlocked(blockerId, blockedId string) (bool, error) {
+	return service.blockStore.IsBlocked(blockerId, blockedId)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+type CallService struct {
+	callStore db.CallStore
+}
+
+func NewCallService(callStore db.CallStore) CallService {
+	return CallService{callStore}
+}
+
+func (service *CallService) RecordCall(record llm.CallRecord) error {
+	var strategy *string
+	if record.Strategy != "" {
+		strategy = &record.Strategy
+	}
+
+	return service.callStore.CreateCall(db.CreateCall{
+		CorrelationId: record.CorrelationId,
+		UserId:        record.UserId,
+		Caller:        record.Caller,
+		Model:         record.Model.String(),
+		System:        record.System,
+		Prompt:        record.Prompt,
+		Response:      record.Response,
+		Outcome:       record.Outcome,
+		Strategy:      strategy,
+		Error:         record.Error,
+		Step:          record.Step,
+		Retry:         record.Retry,
+		LatencyMs:     record.Latency.Milliseconds(),
+	})
+}
+
+func convertCalls(calls []db.Call) []model.LlmCall {
+	convertedCalls := make([]model.LlmCall, len(calls))
+	for i, call := range calls {
+		convertedCalls[i] = model.LlmCall(call)
+	}
+
+	return convertedCalls
+}
+
+func (service *CallService) GetUserCalls(userId string, limit int) ([]model.LlmCall, error) {
+	calls, err := service.callStore.GetUserCalls(userId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertCalls(calls), nil
+}
+
+func (service *CallService) GetCorrelatedCalls(correlationId string) ([]model.LlmCall, error) {
+	calls, err := service.callStore.GetCorrelatedCalls(correlationId)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertCalls(calls), nil
+}
+
+func (service *CallService) GetMatchCalls(matchId string) ([]model.LlmCall, error) {
+	calls, err := service.callStore.GetMatchCalls(matchId)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertCalls(calls), nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/vector"
+)
+
+type EmbeddingService struct {
+	embeddingStore db.EmbeddingStore
+}
+
+func NewEmbeddingService(embeddingStore db.EmbeddingStore) EmbeddingService {
+	return EmbeddingService{embeddingStore}
+}
+
+func (service *EmbeddingService) GetEmbeddings(model string) ([]vector.Record, error) {
+	embeddings, err := service.embeddingStore.GetEmbeddings(model)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]vector.Record, 0, len(embeddings))
+	for _, embedding := range embeddings {
+		decoded, err := vector.Decode(embedding.Vector)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, vector.Record{
+			Kind:     embedding.Kind,
+			Id:       embedding.OwnerId,
+			Model:    embedding.Model,
+			TextHash: embedding.TextHash,
+			Vector:   decoded,
+		})
+	}
+
+	return records, nil
+}
+
+func (service *EmbeddingService) PutEmbedding(record vector.Record) error {
+	return service.embeddingStore.PutEmbedding(db.Embedding{
+		Kind:     record.Kind,
+		OwnerId:  record.Id,
+		Model:    record.Model,
+		TextHash: record.TextHash,
+		Vector:   vector.Encode(record.Vector),
+	})
+}
+
+func (service *EmbeddingService) DeleteEmbedding(kind, id string) error {
+	return service.embeddingStore.DeleteEmbedding(kind, id)
+}
+
+func (service *EmbeddingService) DeleteEmbeddings(id string) error {
+	return service.embeddingStore.DeleteEmbeddings(id)
+}
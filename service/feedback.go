@@ -0,0 +1,217 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nvdaz/find-a-friend-api/compatibility"
+	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+var (
+	ErrInvalidFeedback = errors.New("rating must be between 1 and 5 and tags must be known feedback tags")
+	ErrMatchNotRatable = errors.New("only active or closed matches can be rated")
+)
+
+// Weights learned across all users are reused for this long, as replies and
+// declines shift them too, or until new feedback is submitted.
+const weightsMaxAge = 15 * time.Minute
+
+type weightCache struct {
+	mu        sync.Mutex
+	weights   compatibility.Weights
+	learnedAt time.Time
+}
+
+type FeedbackService struct {
+	feedbackStore db.FeedbackStore
+	matchStore    db.MatchStore
+	weights       *weightCache
+}
+
+func NewFeedbackService(feedbackStore db.FeedbackStore, matchStore db.MatchStore) FeedbackService {
+	return FeedbackService{feedbackStore, matchStore, &weightCache{}}
+}
+
+func (service *FeedbackService) RateMatch(matchId string, feedback model.MatchFeedback) error {
+	if feedback.Rating < 1 || feedback.Rating > 5 {
+		return ErrInvalidFeedback
+	}
+	for _, tag := range feedback.Tags {
+		if !slices.Contains(model.FeedbackTags, tag) {
+			return ErrInvalidFeedback
+		}
+	}
+
+	match, err := service.matchStore.GetMatch(matchId)
+	if err != nil {
+		return err
+	}
+	if status := model.MatchStatus(match.Status); status != model.MatchStatusActive && status != model.MatchStatusClosed {
+		return ErrMatchNotRatable
+	}
+
+	tags := feedback.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	tagsData, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+
+	if err := service.feedbackStore.PutFeedback(matchId, feedback.Rating, string(tagsData)); err != nil {
+		return err
+	}
+
+	service.weights.invalidate()
+
+	return nil
+}
+
+func samplesOf(signals []db.MatchSignals) ([]compatibility.Sample, error) {
+	samples := make([]compatibility.Sample, 0, len(signals))
+	for _, signal := range signals {
+		var scores model.CompatibilityScores
+		if err := json.Unmarshal([]byte(signal.Compatibility), &scores); err != nil {
+			return nil, err
+		}
+
+		engagement := compatibility.Engagement(signal.Sent, signal.Received)
+		if model.MatchStatus(signal.Status) == model.MatchStatusDeclined {
+			engagement = 0
+		}
+
+		samples = append(samples, compatibility.Sample{
+			Scores:  scores,
+			Outcome: compatibility.Outcome(signal.Rating, engagement),
+		})
+	}
+
+	return samples, nil
+}
+
+func (service *FeedbackService) samples(userId string) ([]compatibility.Sample, error) {
+	signals, err := service.feedbackStore.GetMatchSignals(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return samplesOf(signals)
+}
+
+// GetWeights learns compatibility weights from every user's matches and then
+// adjusts them for how the user's own matches went. An empty userId gives the
+// weights learned across all users, which are cached between calls.
+func (service *FeedbackService) GetWeights(userId string) (compatibility.Weights, error) {
+	weights, err := service.sharedWeights()
+	if err != nil {
+		return compatibility.Weights{}, err
+	}
+	if userId == "" {
+		return weights, nil
+	}
+
+	userSamples, err := service.samples(userId)
+	if err != nil {
+		return compatibility.Weights{}, err
+	}
+
+	return compatibility.LearnWeights(weights, userSamples), nil
+}
+
+func (service *FeedbackService) sharedWeights() (compatibility.Weights, error) {
+	cache := service.weights
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if !cache.learnedAt.IsZero() && time.Since(cache.learnedAt) < weightsMaxAge {
+		return cache.weights, nil
+	}
+
+	samples, err := service.samples("")
+	if err != nil {
+		return compatibility.Weights{}, err
+	}
+
+	cache.weights = compatibility.LearnWeights(compatibility.DefaultWeights, samples)
+	cache.learnedAt = time.Now()
+
+	return cache.weights, nil
+}
+
+func (cache *weightCache) invalidate() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.learnedAt = time.Time{}
+}
+
+func (service *FeedbackService) GetFeedbackReport() (model.FeedbackReport, error) {
+	report := model.FeedbackReport{
+		Ratings:        map[string]int{},
+		Tags:           map[string]int{},
+		DefaultWeights: model.FeatureWeights(compatibility.DefaultWeights),
+	}
+
+	ratings, err := service.feedbackStore.GetRatingCounts()
+	if err != nil {
+		return model.FeedbackReport{}, err
+	}
+
+	var total int
+	for rating := 1; rating <= 5; rating++ {
+		report.Ratings[strconv.Itoa(rating)] = 0
+	}
+	for _, count := range ratings {
+		rating, err := strconv.Atoi(count.Key)
+		if err != nil {
+			return model.FeedbackReport{}, err
+		}
+		report.Ratings[count.Key] = count.Count
+		report.Rated += count.Count
+		total += rating * count.Count
+	}
+	if report.Rated > 0 {
+		report.AverageRating = float64(total) / float64(report.Rated)
+	}
+
+	tags, err := service.feedbackStore.GetTagCounts()
+	if err != nil {
+		return model.FeedbackReport{}, err
+	}
+	for _, count := range tags {
+		report.Tags[count.Key] = count.Count
+	}
+
+	signals, err := service.feedbackStore.GetMatchSignals("")
+	if err != nil {
+		return model.FeedbackReport{}, err
+	}
+
+	samples, err := samplesOf(signals)
+	if err != nil {
+		return model.FeedbackReport{}, err
+	}
+
+	var replied int
+	for i, signal := range signals {
+		if signal.Sent > 0 && signal.Received > 0 {
+			replied++
+		}
+		report.AverageOutcome += samples[i].Outcome / float64(len(samples))
+	}
+	report.Matches = len(signals)
+	if report.Matches > 0 {
+		report.ReplyRate = float64(replied) / float64(report.Matches)
+	}
+
+	report.LearnedWeights = model.FeatureWeights(compatibility.LearnWeights(compatibility.DefaultWeights, samples))
+
+	return report, nil
+}
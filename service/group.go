@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/nvdaz/find-a-friend-api/compatibility"
+	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/llm/match"
+	"github.com/nvdaz/find-a-friend-api/llm/prompt"
+	"github.com/nvdaz/find-a-friend-api/model"
+	"golang.org/x/sync/errgroup"
+)
+
+var ErrNoGroup = errors.New("not enough users with shared interests to form a group")
+
+type GroupService struct {
+	userService UserService
+	groupStore  db.GroupStore
+	llmClient   *llm.Client
+}
+
+func NewGroupService(userService UserService, groupStore db.GroupStore, llmClient *llm.Client) GroupService {
+	return GroupService{userService, groupStore, llmClient}
+}
+
+func convertGroup(group db.Group) (model.Group, error) {
+	var themes []string
+	if err := json.Unmarshal([]byte(group.Themes), &themes); err != nil {
+		return model.Group{}, err
+	}
+
+	var promptVersions map[string]string
+	if group.PromptVersions != nil {
+		if err := json.Unmarshal([]byte(*group.PromptVersions), &promptVersions); err != nil {
+			return model.Group{}, err
+		}
+	}
+
+	members := make([]model.GroupMember, len(group.Members))
+	for i, member := range group.Members {
+		members[i] = model.GroupMember(member)
+	}
+
+	return model.Group{
+		Id:             group.Id,
+		Name:           group.Name,
+		Intro:          group.Intro,
+		Themes:         themes,
+		PromptVersions: promptVersions,
+		Members:        members,
+		CreatedAt:      group.CreatedAt,
+	}, nil
+}
+
+func (service *GroupService) GetGroup(id string) (model.Group, error) {
+	group, err := service.groupStore.GetGroup(id)
+	if err != nil {
+		return model.Group{}, err
+	}
+
+	return convertGroup(group)
+}
+
+func (service *GroupService) GetUserGroups(userId string) ([]model.Group, error) {
+	ids, err := service.groupStore.GetUserGroupIds(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]model.Group, len(ids))
+	for i, id := range ids {
+		groups[i], err = service.GetGroup(id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return groups, nil
+}
+
+func (service *GroupService) groupable() (func(a, b string) bool, error) {
+	pairs, err := service.groupStore.GetUngroupablePairs()
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := map[[2]string]bool{}
+	for _, pair := range pairs {
+		excluded[[2]string{pair.UserId, pair.OtherId}] = true
+		excluded[[2]string{pair.OtherId, pair.UserId}] = true
+	}
+
+	return func(a, b string) bool {
+		return !excluded[[2]string{a, b}]
+	}, nil
+}
+
+func proposedGroup(group compatibility.Group) model.Group {
+	members := make([]model.GroupMember, len(group.Members))
+	for i, member := range group.Members {
+		members[i] = model.GroupMember{UserId: member.Id, Name: member.Name}
+	}
+
+	return model.Group{Themes: group.Themes, Affinity: group.Affinity, Members: members}
+}
+
+// FormUserGroup forms a new group around the user from people they have not
+// been grouped with before.
+func (service *GroupService) FormUserGroup(ctx context.Context, id string) (model.Group, error) {
+	ctx = llm.WithPriority(llm.WithUser(ctx, id), llm.PriorityInteractive)
+
+	user, err := service.userService.GetUser(ctx, id)
+	if err != nil {
+		return model.Group{}, err
+	}
+
+	users, err := service.userService.GetAllUsers(id)
+	if err != nil {
+		return model.Group{}, err
+	}
+
+	allowed, err := service.groupable()
+	if err != nil {
+		return model.Group{}, err
+	}
+
+	group, ok := compatibility.FormGroup(*user, users, allowed)
+	if !ok {
+		return model.Group{}, ErrNoGroup
+	}
+
+	return service.createGroup(ctx, group)
+}
+
+// RunGroupBatch clusters the whole user pool into groups. Nothing is written
+// in a dry run. A group that fails to be created is reported with its error
+// and the rest are still created.
+func (service *GroupService) RunGroupBatch(ctx context.Context, dryRun bool) (model.GroupRun, error) {
+	users, err := service.userService.GetAllUsers("")
+	if err != nil {
+		return model.GroupRun{}, err
+	}
+
+	allowed, err := service.groupable()
+	if err != nil {
+		return model.GroupRun{}, err
+	}
+
+	groups := compatibility.Groups(users, allowed)
+	run := model.GroupRun{DryRun: dryRun, Users: len(users), Groups: make([]model.Group, len(groups))}
+	for i, group := range groups {
+		if dryRun {
+			run.Groups[i] = proposedGroup(group)
+			continue
+		}
+
+		created, err := service.createGroup(llm.WithUser(ctx, group.Members[0].Id), group)
+		if err != nil {
+			message := err.Error()
+			run.Groups[i] = proposedGroup(group)
+			run.Groups[i].Error = &message
+			continue
+		}
+		run.Groups[i] = created
+	}
+
+	return run, nil
+}
+
+// createGroup writes the group intro and an explanation for every member,
+// then stores the group.
+func (service *GroupService) createGroup(ctx context.Context, group compatibility.Group) (model.Group, error) {
+	recorder := prompt.NewRecorder()
+	ctx = prompt.WithRecorder(ctx, recorder)
+	correlationId := uuid.New().String()
+	ctx = llm.WithCorrelationId(ctx, correlationId)
+
+	var name, intro string
+	explanations := make([]string, len(group.Members))
+
+	errs, errsCtx := errgroup.WithContext(ctx)
+	errs.Go(func() error {
+		var err error
+		name, intro, err = match.IntroduceGroup(errsCtx, service.llmClient, group.Members, group.Themes)
+		return err
+	})
+
+	var explanationsMu sync.Mutex
+	for i, member := range group.Members {
+		others := make([]model.User, 0, len(group.Members)-1)
+		for _, other := range group.Members {
+			if other.Id != member.Id {
+				others = append(others, other)
+			}
+		}
+
+		errs.Go(func() error {
+			explanation, err := match.ExplainGroupToUser(errsCtx, service.llmClient, member, others, group.Themes)
+			if err != nil {
+				return err
+			}
+
+			explanationsMu.Lock()
+			explanations[i] = explanation
+			explanationsMu.Unlock()
+			return nil
+		})
+	}
+
+	if err := errs.Wait(); err != nil {
+		return model.Group{}, err
+	}
+
+	themesData, err := json.Marshal(group.Themes)
+	if err != nil {
+		return model.Group{}, err
+	}
+
+	promptVersions := recorder.Versions()
+	promptVersionsData, err := json.Marshal(promptVersions)
+	if err != nil {
+		return model.Group{}, err
+	}
+
+	members := make([]db.CreateGroupMember, len(group.Members))
+	for i, member := range group.Members {
+		members[i] = db.CreateGroupMember{UserId: member.Id, Explanation: explanations[i]}
+	}
+
+	groupId, err := service.groupStore.CreateGroup(db.CreateGroup{
+		Name:           name,
+		Intro:          intro,
+		Themes:         string(themesData),
+		PromptVersions: string(promptVersionsData),
+		CorrelationId:  correlationId,
+		Members:        members,
+	})
+	if err != nil {
+		return model.Group{}, err
+	}
+
+	created := proposedGroup(group)
+	created.Id = *groupId
+	created.Name = name
+	created.Intro = intro
+	created.PromptVersions = promptVersions
+	for i := range created.Members {
+		created.Members[i].Explanation = explanations[i]
+	}
+
+	return created, nil
+}
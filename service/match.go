@@ -1,21 +1,72 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/nvdaz/find-a-friend-api/compatibility"
 	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/llm"
 	"github.com/nvdaz/find-a-friend-api/llm/match"
+	"github.com/nvdaz/find-a-friend-api/llm/prompt"
 	"github.com/nvdaz/find-a-friend-api/model"
 )
 
+// Matches not answered by both users within MatchExpiry expire.
+const MatchExpiry = 7 * 24 * time.Hour
+
+var ErrInvalidTransition = errors.New("invalid match status transition")
+
 type MatchService struct {
-	UserService UserService
-	matchStore  db.MatchStore
+	UserService     UserService
+	matchStore      db.MatchStore
+	matchRunStore   db.MatchRunStore
+	feedbackService FeedbackService
+	llmClient       *llm.Client
+	retrieval       match.Retrieval
 }
 
-func NewMatchService(userService UserService, matchStore db.MatchStore) MatchService {
-	return MatchService{userService, matchStore}
+func NewMatchService(userService UserService, matchStore db.MatchStore, matchRunStore db.MatchRunStore, feedbackService FeedbackService, llmClient *llm.Client, retrieval match.Retrieval) MatchService {
+	return MatchService{userService, matchStore, matchRunStore, feedbackService, llmClient, retrieval}
+}
+
+func convertMatch(match db.Match) (model.Match, error) {
+	var promptVersions map[string]string
+	if match.PromptVersions != nil {
+		if err := json.Unmarshal([]byte(*match.PromptVersions), &promptVersions); err != nil {
+			return model.Match{}, err
+		}
+	}
+
+	var scores *model.CompatibilityScores
+	if match.Compatibility != nil {
+		if err := json.Unmarshal([]byte(*match.Compatibility), &scores); err != nil {
+			return model.Match{}, err
+		}
+	}
+
+	var explanation *model.MatchExplanation
+	if match.Explanation != nil {
+		if err := json.Unmarshal([]byte(*match.Explanation), &explanation); err != nil {
+			return model.Match{}, err
+		}
+	}
+
+	return model.Match{
+		Id:             match.Id,
+		UserId:         match.UserId,
+		OtherId:        match.OtherId,
+		Reason:         match.Reason,
+		PromptVersions: promptVersions,
+		Compatibility:  scores,
+		Explanation:    explanation,
+		Status:         model.MatchStatus(match.Status),
+		ExpiresAt:      match.ExpiresAt,
+	}, nil
 }
 
 func (service *MatchService) GetMatch(id string) (model.Match, error) {
@@ -24,13 +75,7 @@ func (service *MatchService) GetMatch(id string) (model.Match, error) {
 		return model.Match{}, err
 	}
 
-	return model.Match{
-		Id:      match.Id,
-		UserId:  match.UserId,
-		OtherId: match.OtherId,
-		Reason:  match.Reason,
-	}, nil
-
+	return convertMatch(match)
 }
 
 func (service *MatchService) GetUserMatches(id string) ([]model.Match, error) {
@@ -41,11 +86,9 @@ func (service *MatchService) GetUserMatches(id string) ([]model.Match, error) {
 
 	convertedMatches := make([]model.Match, len(matches))
 	for i, match := range matches {
-		convertedMatches[i] = model.Match{
-			Id:      match.Id,
-			UserId:  match.UserId,
-			OtherId: match.OtherId,
-			Reason:  match.Reason,
+		convertedMatches[i], err = convertMatch(match)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -57,7 +100,6 @@ func (service *MatchService) GetAllNonMatchedUsers(id string) ([]model.User, err
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println("Users", users)
 
 	convertedUsers := make([]model.User, len(users))
 	for i, user := range users {
@@ -82,8 +124,10 @@ func (service *MatchService) GetAllNonMatchedUsers(id string) ([]model.User, err
 	return convertedUsers, nil
 }
 
-func (service *MatchService) GenerateUserMatch(id string) (model.Match, error) {
-	user, err := service.UserService.GetUser(id)
+func (service *MatchService) GenerateUserMatch(ctx context.Context, id string) (model.Match, error) {
+	ctx = llm.WithPriority(llm.WithUser(ctx, id), llm.PriorityInteractive)
+
+	user, err := service.UserService.GetUser(ctx, id)
 	if err != nil {
 		return model.Match{}, err
 	}
@@ -93,54 +137,204 @@ func (service *MatchService) GenerateUserMatch(id string) (model.Match, error) {
 		return model.Match{}, err
 	}
 
-	matchedUserId, err := match.GenerateMatch(*user, otherUsers)
+	weights, err := service.feedbackService.GetWeights(id)
 	if err != nil {
 		return model.Match{}, err
 	}
 
+	recorder := prompt.NewRecorder()
+	ctx = prompt.WithRecorder(ctx, recorder)
+	correlationId := uuid.New().String()
+	ctx = llm.WithCorrelationId(ctx, correlationId)
+	models := llm.NewModelRecorder()
+	ctx = llm.WithModelRecorder(ctx, models)
+
+	start := time.Now()
+	run, err := match.GenerateMatch(ctx, service.llmClient, service.retrieval, *user, otherUsers, weights)
+
+	// The run is recorded however it ends, so failed runs can be traced too.
+	record := func(outcome model.MatchRunOutcome, err error) {
+		run.TimingsMs["total"] = time.Since(start).Milliseconds()
+		run.CorrelationId = correlationId
+		run.Models = models.Models()
+		run.PromptVersions = recorder.Versions()
+		run.Outcome = outcome
+		if err != nil {
+			message := err.Error()
+			run.Error = &message
+		}
+		if err := service.createMatchRun(run); err != nil {
+			fmt.Println("Error recording match run", err)
+		}
+	}
+
+	if err != nil {
+		record(model.MatchRunOutcomeFailed, err)
+		return model.Match{}, err
+	}
+
 	matchedUser := model.User{}
 	for _, u := range otherUsers {
-		if u.Id == *matchedUserId {
+		if u.Id == run.ChosenId {
 			matchedUser = u
 			break
 		}
 	}
 
 	if matchedUser.Id == "" {
+		record(model.MatchRunOutcomeNoMatch, nil)
 		return model.Match{}, nil
 	}
 
-	firstMatchReason, err := match.ExplainMatchToUser(*user, matchedUser)
+	explainStart := time.Now()
+	created, err := service.createMatch(ctx, recorder, correlationId, *user, matchedUser)
+	run.TimingsMs["match_cards"] = time.Since(explainStart).Milliseconds()
+	if err != nil {
+		record(model.MatchRunOutcomeFailed, err)
+		return model.Match{}, err
+	}
+
+	run.MatchId = &created.Id
+	record(model.MatchRunOutcomeMatched, nil)
+
+	return created, nil
+}
+
+// createMatch explains the match to both users and stores it as pending.
+func (service *MatchService) createMatch(ctx context.Context, recorder *prompt.Recorder, correlationId string, user, matchedUser model.User) (model.Match, error) {
+	firstExplanation, err := match.ExplainMatchToUser(ctx, service.llmClient, user, matchedUser)
+	if err != nil {
+		return model.Match{}, err
+	}
+
+	secondExplanation, err := match.ExplainMatchToUser(ctx, service.llmClient, matchedUser, user)
+	if err != nil {
+		return model.Match{}, err
+	}
+
+	firstExplanationData, err := json.Marshal(firstExplanation)
 	if err != nil {
 		return model.Match{}, err
 	}
 
-	secondMatchReason, err := match.ExplainMatchToUser(matchedUser, *user)
+	secondExplanationData, err := json.Marshal(secondExplanation)
 	if err != nil {
 		return model.Match{}, err
 	}
 
+	scores := compatibility.Score(user.Profile, matchedUser.Profile)
+	scoresData, err := json.Marshal(scores)
+	if err != nil {
+		return model.Match{}, err
+	}
+
+	promptVersions := recorder.Versions()
+	promptVersionsData, err := json.Marshal(promptVersions)
+	if err != nil {
+		return model.Match{}, err
+	}
+
+	expiresAt := time.Now().Add(MatchExpiry).UTC().Format(time.DateTime)
 	matchId, err := service.matchStore.CreateMatch(db.CreateMatch{
-		UserId:  user.Id,
-		OtherId: *matchedUserId,
-		Reason:  firstMatchReason,
+		UserId:         user.Id,
+		OtherId:        matchedUser.Id,
+		Reason:         firstExplanation.Reason,
+		PromptVersions: string(promptVersionsData),
+		CorrelationId:  correlationId,
+		Compatibility:  string(scoresData),
+		Explanation:    string(firstExplanationData),
+		ExpiresAt:      expiresAt,
 	}, db.CreateMatch{
-		UserId:  *matchedUserId,
-		OtherId: user.Id,
-		Reason:  secondMatchReason,
+		UserId:         matchedUser.Id,
+		OtherId:        user.Id,
+		Reason:         secondExplanation.Reason,
+		PromptVersions: string(promptVersionsData),
+		CorrelationId:  correlationId,
+		Compatibility:  string(scoresData),
+		Explanation:    string(secondExplanationData),
+		ExpiresAt:      expiresAt,
 	})
 	if err != nil {
 		return model.Match{}, err
 	}
 
 	return model.Match{
-		Id:      *matchId,
-		UserId:  user.Id,
-		OtherId: *matchedUserId,
-		Reason:  firstMatchReason,
+		Id:             *matchId,
+		UserId:         user.Id,
+		OtherId:        matchedUser.Id,
+		Reason:         firstExplanation.Reason,
+		PromptVersions: promptVersions,
+		Compatibility:  &scores,
+		Explanation:    &firstExplanation,
+		Status:         model.MatchStatusPending,
+		ExpiresAt:      &expiresAt,
 	}, nil
 }
 
+func (service *MatchService) AcceptMatch(id string) (model.Match, error) {
+	err := service.matchStore.TransitionMatch(id, func(status, otherStatus string) (string, string, error) {
+		if model.MatchStatus(status) != model.MatchStatusPending {
+			return "", "", ErrInvalidTransition
+		}
+
+		switch model.MatchStatus(otherStatus) {
+		case model.MatchStatusAccepted:
+			return string(model.MatchStatusActive), string(model.MatchStatusActive), nil
+		case model.MatchStatusPending:
+			return string(model.MatchStatusAccepted), otherStatus, nil
+		default:
+			return "", "", ErrInvalidTransition
+		}
+	})
+	if errors.Is(err, db.ErrMatchConflict) {
+		return model.Match{}, ErrInvalidTransition
+	}
+	if err != nil {
+		return model.Match{}, err
+	}
+
+	return service.GetMatch(id)
+}
+
+func (service *MatchService) DeclineMatch(id string) (model.Match, error) {
+	err := service.matchStore.TransitionMatch(id, func(status, otherStatus string) (string, string, error) {
+		switch model.MatchStatus(status) {
+		case model.MatchStatusPending, model.MatchStatusAccepted:
+			return string(model.MatchStatusDeclined), string(model.MatchStatusClosed), nil
+		default:
+			return "", "", ErrInvalidTransition
+		}
+	})
+	if errors.Is(err, db.ErrMatchConflict) {
+		return model.Match{}, ErrInvalidTransition
+	}
+	if err != nil {
+		return model.Match{}, err
+	}
+
+	return service.GetMatch(id)
+}
+
+// Unmatch closes the match for both users and archives their conversation.
+func (service *MatchService) Unmatch(id string) error {
+	err := service.matchStore.CloseMatch(id, func(status, otherStatus string) (string, string, error) {
+		switch model.MatchStatus(status) {
+		case model.MatchStatusPending, model.MatchStatusAccepted, model.MatchStatusActive:
+			return string(model.MatchStatusClosed), string(model.MatchStatusClosed), nil
+		default:
+			return "", "", ErrInvalidTransition
+		}
+	})
+	if errors.Is(err, db.ErrMatchConflict) {
+		return ErrInvalidTransition
+	}
+	return err
+}
+
+func (service *MatchService) ExpireMatches() (int64, error) {
+	return service.matchStore.ExpireMatches()
+}
+
 func (service *MatchService) GetMatchedUsers(id string) ([]model.User, error) {
 	users, err := service.matchStore.GetMatchedUsers(id)
 	if err != nil {
@@ -1,17 +1,22 @@
 package service
 
 import (
+	"errors"
+
 	"github.com/nvdaz/find-a-friend-api/db"
 	"github.com/nvdaz/find-a-friend-api/model"
 )
 
+var ErrBlocked = errors.New("sender is blocked by receiver")
+
 type MessageService struct {
 	messagesStore db.MessageStore
+	blockService  BlockService
 	userService   UserService
 }
 
-func NewMessagesService(messagesStore db.MessageStore, userService UserService) MessageService {
-	return MessageService{messagesStore, userService}
+func NewMessagesService(messagesStore db.MessageStore, blockService BlockService, userService UserService) MessageService {
+	return MessageService{messagesStore, blockService, userService}
 }
 
 func (service *MessageService) GetMessages(senderId, receiverId string, limit int) ([]model.Message, error) {
@@ -43,7 +48,15 @@ func (service *MessageService) PollMessages(senderId, receiverId, after string,
 }
 
 func (service *MessageService) CreateMessage(senderId, receiverId, message string) error {
-	err := service.messagesStore.CreateMessage(senderId, receiverId, message)
+	blocked, err := service.blockService.IsBlocked(receiverId, senderId)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+
+	err = service.messagesStore.CreateMessage(senderId, receiverId, message)
 	if err != nil {
 		return err
 	}
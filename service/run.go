@@ -0,0 +1,78 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+func (service *MatchService) createMatchRun(run model.MatchRun) error {
+	fields := []any{run.Weights, run.Candidates, run.Models, run.TimingsMs, run.PromptVersions}
+	encoded := make([]string, len(fields))
+	for i, value := range fields {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		encoded[i] = string(data)
+	}
+
+	return service.matchRunStore.CreateMatchRun(db.CreateMatchRun{
+		MatchId:        run.MatchId,
+		UserId:         run.UserId,
+		CorrelationId:  run.CorrelationId,
+		PoolSize:       run.PoolSize,
+		Weights:        encoded[0],
+		Candidates:     encoded[1],
+		ChosenId:       run.ChosenId,
+		Reasoning:      run.Reasoning,
+		Fallback:       run.Fallback,
+		Models:         encoded[2],
+		Timings:        encoded[3],
+		PromptVersions: encoded[4],
+		Outcome:        string(run.Outcome),
+		Error:          run.Error,
+	})
+}
+
+// GetMatchRun returns the run that generated the match with the given ID, in
+// either direction.
+func (service *MatchService) GetMatchRun(matchId string) (model.MatchRun, error) {
+	run, err := service.matchRunStore.GetMatchRun(matchId)
+	if err != nil {
+		return model.MatchRun{}, err
+	}
+
+	converted := model.MatchRun{
+		Id:            run.Id,
+		MatchId:       run.MatchId,
+		UserId:        run.UserId,
+		CorrelationId: run.CorrelationId,
+		PoolSize:      run.PoolSize,
+		ChosenId:      run.ChosenId,
+		Reasoning:     run.Reasoning,
+		Fallback:      run.Fallback,
+		Outcome:       model.MatchRunOutcome(run.Outcome),
+		Error:         run.Error,
+		CreatedAt:     run.CreatedAt,
+	}
+
+	fields := []struct {
+		data  string
+		value any
+	}{
+		{run.Weights, &converted.Weights},
+		{run.Candidates, &converted.Candidates},
+		{run.Models, &converted.Models},
+		{run.Timings, &converted.TimingsMs},
+		{run.PromptVersions, &converted.PromptVersions},
+	}
+	for _, field := range fields {
+		if err := json.Unmarshal([]byte(field.data), field.value); err != nil {
+			return model.MatchRun{}, err
+		}
+	}
+
+	return converted, nil
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessionTTL is how long a token from registering or logging in lasts.
+const SessionTTL = 30 * 24 * time.Hour
+
+var ErrInvalidSession = errors.New("invalid session token")
+
+// SessionService issues and verifies the tokens that identify a logged in
+// user. A token is the user's id and an expiry, signed with the secret.
+type SessionService struct {
+	secret []byte
+}
+
+func NewSessionService(secret []byte) SessionService {
+	return SessionService{secret}
+}
+
+// NewSessionServiceFromEnv signs tokens with SESSION_SECRET, or with a random
+// secret when it is unset, in which case sessions end when the server stops.
+func NewSessionServiceFromEnv() SessionService {
+	secret := []byte(os.Getenv("SESSION_SECRET"))
+	if len(secret) == 0 {
+		fmt.Println("SESSION_SECRET is not set, sessions will not survive a restart")
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+
+	return NewSessionService(secret)
+}
+
+func (service *SessionService) sign(payload string) string {
+	mac := hmac.New(sha256.New, service.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (service *SessionService) Issue(userId string) string {
+	payload := userId + "." + strconv.FormatInt(time.Now().Add(SessionTTL).Unix(), 10)
+	return payload + "." + service.sign(payload)
+}
+
+// Verify returns the id of the user the token was issued to.
+func (service *SessionService) Verify(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidSession
+	}
+	userId, expiry, signature := parts[0], parts[1], parts[2]
+
+	if !hmac.Equal([]byte(signature), []byte(service.sign(userId+"."+expiry))) {
+		return "", ErrInvalidSession
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().Unix() >= expiresAt {
+		return "", ErrInvalidSession
+	}
+
+	return userId, nil
+}
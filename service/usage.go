@@ -0,0 +1,47 @@
+package service
+
+import (
+	"time"
+
+	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/llm"
+	"github.com/nvdaz/find-a-friend-api/model"
+)
+
+type UsageService struct {
+	usageStore db.UsageStore
+}
+
+func NewUsageService(usageStore db.UsageStore) UsageService {
+	return UsageService{usageStore}
+}
+
+func (service *UsageService) RecordUsage(record llm.UsageRecord) error {
+	return service.usageStore.CreateUsage(db.CreateUsage{
+		UserId:       record.UserId,
+		Feature:      record.Feature,
+		Model:        record.Model.String(),
+		InputTokens:  record.InputTokens,
+		OutputTokens: record.OutputTokens,
+		Cost:         record.Cost,
+		LatencyMs:    record.Latency.Milliseconds(),
+	})
+}
+
+func (service *UsageService) GetUserMonthlyCost(userId string) (float64, error) {
+	return service.usageStore.GetUserMonthlyCost(userId)
+}
+
+func (service *UsageService) GetUsageSummary(groupBy string, since time.Time) ([]model.UsageSummary, error) {
+	summaries, err := service.usageStore.GetUsageSummary(groupBy, since.UTC().Format(time.DateTime))
+	if err != nil {
+		return nil, err
+	}
+
+	convertedSummaries := make([]model.UsageSummary, len(summaries))
+	for i, summary := range summaries {
+		convertedSummaries[i] = model.UsageSummary(summary)
+	}
+
+	return convertedSummaries, nil
+}
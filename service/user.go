@@ -1,24 +1,31 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nvdaz/find-a-friend-api/db"
+	"github.com/nvdaz/find-a-friend-api/llm"
 	"github.com/nvdaz/find-a-friend-api/llm/profile"
+	"github.com/nvdaz/find-a-friend-api/llm/prompt"
 	"github.com/nvdaz/find-a-friend-api/model"
+	"github.com/nvdaz/find-a-friend-api/vector"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
 	userStore    db.UserStore
 	messageStore db.MessageStore
+	llmClient    *llm.Client
+	vectorIndex  *vector.Index
 }
 
-func NewUserService(userStore db.UserStore, messageStore db.MessageStore) UserService {
-	return UserService{userStore, messageStore}
+func NewUserService(userStore db.UserStore, messageStore db.MessageStore, llmClient *llm.Client, vectorIndex *vector.Index) UserService {
+	return UserService{userStore, messageStore, llmClient, vectorIndex}
 }
 
 func needsUpdate(user *db.User) bool {
@@ -49,7 +56,9 @@ func (service *UserService) MarkUserAsUpdated(id string) error {
 	return service.userStore.MarkUserAsUpdated(id)
 }
 
-func (service *UserService) GetUser(id string) (*model.User, error) {
+func (service *UserService) GetUser(ctx context.Context, id string) (*model.User, error) {
+	ctx = llm.WithUser(ctx, id)
+
 	user, err := service.userStore.GetUser(id)
 	if err != nil {
 		return nil, err
@@ -62,8 +71,6 @@ func (service *UserService) GetUser(id string) (*model.User, error) {
 			return nil, err
 		}
 
-		fmt.Println("Profile", profile)
-
 		return &model.User{
 			Id:      user.Id,
 			Name:    user.Name,
@@ -83,7 +90,12 @@ func (service *UserService) GetUser(id string) (*model.User, error) {
 	}
 	partitionedConversations := partitionConversations(id, conversations)
 
-	profile, err := profile.GenerateProfile(id, questions, partitionedConversations)
+	recorder := prompt.NewRecorder()
+	ctx = prompt.WithRecorder(ctx, recorder)
+	correlationId := uuid.New().String()
+	ctx = llm.WithCorrelationId(ctx, correlationId)
+
+	profile, err := profile.GenerateProfile(ctx, service.llmClient, id, questions, partitionedConversations)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +105,16 @@ func (service *UserService) GetUser(id string) (*model.User, error) {
 		return nil, err
 	}
 
-	service.userStore.UpdateUserProfile(id, string(data))
+	promptVersions, err := json.Marshal(recorder.Versions())
+	if err != nil {
+		return nil, err
+	}
+
+	service.userStore.UpdateUserProfile(id, string(data), string(promptVersions), correlationId)
+
+	if err := service.indexProfile(ctx, id, profile); err != nil {
+		fmt.Println("Error indexing profile", err)
+	}
 
 	return &model.User{
 		Id:      user.Id,
@@ -103,8 +124,50 @@ func (service *UserService) GetUser(id string) (*model.User, error) {
 	}, nil
 }
 
-func (service *UserService) GetAllUsers() ([]model.User, error) {
-	users, err := service.userStore.GetAllUsers()
+func profileEmbeddingItems(id string, profile *model.InternalProfile) []vector.Item {
+	interests := make([]string, len(profile.Interests))
+	for i, interest := range profile.Interests {
+		interests[i] = interest.Interest
+	}
+
+	topics := make([]string, len(profile.Topics))
+	for i, topic := range profile.Topics {
+		topics[i] = topic.Topic
+	}
+
+	return []vector.Item{
+		{Key: vector.Key{Kind: vector.KindSummary, Id: id}, Text: profile.Summary},
+		{Key: vector.Key{Kind: vector.KindInterests, Id: id}, Text: strings.Join(interests, ", ")},
+		{Key: vector.Key{Kind: vector.KindTopics, Id: id}, Text: strings.Join(topics, ", ")},
+		{Key: vector.Key{Kind: vector.KindLookingFor, Id: id}, Text: profile.LookingFor},
+	}
+}
+
+func (service *UserService) indexProfile(ctx context.Context, id string, profile *model.InternalProfile) error {
+	return service.vectorIndex.Put(ctx, profileEmbeddingItems(id, profile))
+}
+
+// IndexProfiles embeds every generated profile that is missing from the
+// vector index or has changed since it was indexed.
+func (service *UserService) IndexProfiles(ctx context.Context) error {
+	users, err := service.GetAllUsers("")
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := service.indexProfile(ctx, user.Id, user.Profile); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAllUsers lists users with a profile, leaving out anyone with a block
+// against or from viewerId.
+func (service *UserService) GetAllUsers(viewerId string) ([]model.User, error) {
+	users, err := service.userStore.GetAllUsers(viewerId)
 	if err != nil {
 		return nil, err
 	}
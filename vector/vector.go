@@ -0,0 +1,281 @@
+package vector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	KindSummary    = "summary"
+	KindInterests  = "interests"
+	KindTopics     = "topics"
+	KindLookingFor = "looking_for"
+)
+
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	EmbeddingModel() string
+}
+
+type Record struct {
+	Kind     string
+	Id       string
+	Model    string
+	TextHash string
+	Vector   []float32
+}
+
+type Store interface {
+	GetEmbeddings(model string) ([]Record, error)
+	PutEmbedding(record Record) error
+	DeleteEmbedding(kind, id string) error
+	DeleteEmbeddings(id string) error
+}
+
+type Key struct {
+	Kind string
+	Id   string
+}
+
+type Item struct {
+	Key
+	Text string
+}
+
+type Result struct {
+	Id    string
+	Score float64
+}
+
+type entry struct {
+	textHash string
+	vector   []float32
+}
+
+// Index keeps every embedding in memory for brute force cosine search and
+// writes through to the store so it survives restarts.
+type Index struct {
+	store    Store
+	embedder Embedder
+	mu       sync.RWMutex
+	entries  map[Key]entry
+}
+
+func NewIndex(store Store, embedder Embedder) *Index {
+	return &Index{store: store, embedder: embedder, entries: map[Key]entry{}}
+}
+
+// Load reads the stored embeddings made by the current embedding model.
+func (index *Index) Load() error {
+	records, err := index.store.GetEmbeddings(index.embedder.EmbeddingModel())
+	if err != nil {
+		return err
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	for _, record := range records {
+		index.entries[Key{record.Kind, record.Id}] = entry{record.TextHash, normalize(record.Vector)}
+	}
+
+	return nil
+}
+
+func hashText(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// Put embeds and stores the items whose text has changed since they were
+// last indexed. Items with empty text are removed.
+func (index *Index) Put(ctx context.Context, items []Item) error {
+	var stale, removed []Item
+	var hashes []string
+
+	index.mu.Lock()
+	for _, item := range items {
+		if item.Text == "" {
+			if _, ok := index.entries[item.Key]; ok {
+				delete(index.entries, item.Key)
+				removed = append(removed, item)
+			}
+			continue
+		}
+
+		hash := hashText(item.Text)
+		if existing, ok := index.entries[item.Key]; ok && existing.textHash == hash {
+			continue
+		}
+		stale = append(stale, item)
+		hashes = append(hashes, hash)
+	}
+	index.mu.Unlock()
+
+	for _, item := range removed {
+		if err := index.store.DeleteEmbedding(item.Kind, item.Id); err != nil {
+			return err
+		}
+	}
+
+	if len(stale) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(stale))
+	for i, item := range stale {
+		texts[i] = item.Text
+	}
+
+	vectors, err := index.embedder.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+	if len(vectors) != len(stale) {
+		return fmt.Errorf("expected %d embeddings, got %d", len(stale), len(vectors))
+	}
+
+	model := index.embedder.EmbeddingModel()
+	for i, item := range stale {
+		err := index.store.PutEmbedding(Record{
+			Kind:     item.Kind,
+			Id:       item.Id,
+			Model:    model,
+			TextHash: hashes[i],
+			Vector:   vectors[i],
+		})
+		if err != nil {
+			return err
+		}
+
+		index.mu.Lock()
+		index.entries[item.Key] = entry{hashes[i], normalize(vectors[i])}
+		index.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (index *Index) Delete(id string) error {
+	index.mu.Lock()
+	for key := range index.entries {
+		if key.Id == id {
+			delete(index.entries, key)
+		}
+	}
+	index.mu.Unlock()
+
+	return index.store.DeleteEmbeddings(id)
+}
+
+func (index *Index) Get(key Key) ([]float32, bool) {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	entry, ok := index.entries[key]
+	return entry.vector, ok
+}
+
+// Search returns the k entries of the given kind most similar to query,
+// considering only ids accepted by filter when it is set.
+func (index *Index) Search(kind string, query []float32, k int, filter func(id string) bool) []Result {
+	query = normalize(query)
+
+	index.mu.RLock()
+	results := []Result{}
+	for key, entry := range index.entries {
+		if key.Kind != kind || (filter != nil && !filter(key.Id)) {
+			continue
+		}
+		results = append(results, Result{key.Id, dot(query, entry.vector)})
+	}
+	index.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Id < results[j].Id
+	})
+
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+
+	return results
+}
+
+func (index *Index) SearchText(ctx context.Context, kind, text string, k int, filter func(id string) bool) ([]Result, error) {
+	vectors, err := index.embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("expected 1 embedding, got %d", len(vectors))
+	}
+
+	return index.Search(kind, vectors[0], k, filter), nil
+}
+
+func Cosine(a, b []float32) float64 {
+	return dot(normalize(a), normalize(b))
+}
+
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+
+	return sum
+}
+
+func normalize(vector []float32) []float32 {
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm == 0 {
+		return vector
+	}
+
+	scale := 1 / math.Sqrt(norm)
+	normalized := make([]float32, len(vector))
+	for i, value := range vector {
+		normalized[i] = float32(float64(value) * scale)
+	}
+
+	return normalized
+}
+
+func Encode(vector []float32) []byte {
+	data := make([]byte, 4*len(vector))
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(value))
+	}
+
+	return data
+}
+
+func Decode(data []byte) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid vector length %d", len(data))
+	}
+
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+
+	return vector, nil
+}
@@ -0,0 +1,232 @@
+package vector
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// memoryStore keeps records encoded, as the database does.
+type memoryStore map[Key]storedRecord
+
+type storedRecord struct {
+	model    string
+	textHash string
+	data     []byte
+}
+
+func (store memoryStore) GetEmbeddings(model string) ([]Record, error) {
+	records := []Record{}
+	for key, stored := range store {
+		if stored.model != model {
+			continue
+		}
+		vector, err := Decode(stored.data)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, Record{key.Kind, key.Id, stored.model, stored.textHash, vector})
+	}
+	return records, nil
+}
+
+func (store memoryStore) PutEmbedding(record Record) error {
+	store[Key{record.Kind, record.Id}] = storedRecord{record.Model, record.TextHash, Encode(record.Vector)}
+	return nil
+}
+
+func (store memoryStore) DeleteEmbedding(kind, id string) error {
+	delete(store, Key{kind, id})
+	return nil
+}
+
+func (store memoryStore) DeleteEmbeddings(id string) error {
+	for key := range store {
+		if key.Id == id {
+			delete(store, key)
+		}
+	}
+	return nil
+}
+
+// fixedEmbedder returns the vector listed for each text and counts the texts
+// it is asked to embed.
+type fixedEmbedder struct {
+	model    string
+	vectors  map[string][]float32
+	embedded int
+}
+
+func (embedder *fixedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = embedder.vectors[text]
+	}
+	embedder.embedded += len(texts)
+	return vectors, nil
+}
+
+func (embedder *fixedEmbedder) EmbeddingModel() string {
+	return embedder.model
+}
+
+func testEmbedder() *fixedEmbedder {
+	return &fixedEmbedder{model: "test", vectors: map[string][]float32{
+		"same":      {2, 0},
+		"close":     {1, 1},
+		"unrelated": {0, 3},
+		"opposite":  {-1, 0},
+		"query":     {1, 0},
+	}}
+}
+
+func item(kind, id, text string) Item {
+	return Item{Key{kind, id}, text}
+}
+
+func put(t *testing.T, index *Index, items ...Item) {
+	if err := index.Put(context.Background(), items); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func resultIds(results []Result) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.Id
+	}
+	return ids
+}
+
+func TestSearchRanksByCosineSimilarity(t *testing.T) {
+	index := NewIndex(memoryStore{}, testEmbedder())
+	put(t, index,
+		item(KindSummary, "d", "opposite"),
+		item(KindSummary, "c", "unrelated"),
+		item(KindSummary, "b", "close"),
+		item(KindSummary, "a", "same"),
+		item(KindSummary, "a2", "same"),
+		item(KindInterests, "e", "same"),
+	)
+
+	results := index.Search(KindSummary, []float32{3, 0}, 0, nil)
+	if got, want := resultIds(results), []string{"a", "a2", "b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i, want := range []float64{1, 1, 1 / math.Sqrt2, 0, -1} {
+		if math.Abs(results[i].Score-want) > 1e-6 {
+			t.Errorf("%s scored %v, want %v", results[i].Id, results[i].Score, want)
+		}
+	}
+
+	results = index.Search(KindSummary, []float32{1, 0}, 2, func(id string) bool { return id != "a" })
+	if got, want := resultIds(results), []string{"a2", "b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("filtered search got %v, want %v", got, want)
+	}
+
+	results, err := index.SearchText(context.Background(), KindInterests, "query", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resultIds(results), []string{"e"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("text search got %v, want %v", got, want)
+	}
+}
+
+func TestLoadRestoresStoredEmbeddings(t *testing.T) {
+	store := memoryStore{}
+	index := NewIndex(store, testEmbedder())
+	put(t, index, item(KindSummary, "a", "same"), item(KindSummary, "b", "close"))
+	store[Key{KindSummary, "old"}] = storedRecord{"old-model", "hash", Encode([]float32{1, 0})}
+
+	embedder := testEmbedder()
+	loaded := NewIndex(store, embedder)
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []Key{{KindSummary, "a"}, {KindSummary, "b"}} {
+		want, _ := index.Get(key)
+		if got, ok := loaded.Get(key); !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("loaded %v as %v, want %v", key, got, want)
+		}
+	}
+	if _, ok := loaded.Get(Key{KindSummary, "old"}); ok {
+		t.Error("loaded an embedding made by another model")
+	}
+
+	put(t, loaded, item(KindSummary, "a", "same"), item(KindSummary, "b", "close"))
+	if embedder.embedded != 0 {
+		t.Errorf("re-embedded %d unchanged texts after loading", embedder.embedded)
+	}
+}
+
+func TestPutReembedsOnlyChangedText(t *testing.T) {
+	store := memoryStore{}
+	embedder := testEmbedder()
+	index := NewIndex(store, embedder)
+
+	put(t, index, item(KindSummary, "a", "same"), item(KindSummary, "b", "close"))
+	if embedder.embedded != 2 {
+		t.Fatalf("embedded %d texts, want 2", embedder.embedded)
+	}
+
+	put(t, index, item(KindSummary, "a", "same"), item(KindSummary, "b", "close"))
+	if embedder.embedded != 2 {
+		t.Fatalf("embedded %d texts for unchanged items, want none", embedder.embedded-2)
+	}
+
+	put(t, index, item(KindSummary, "a", "opposite"), item(KindSummary, "b", "close"))
+	if embedder.embedded != 3 {
+		t.Fatalf("embedded %d texts after one change, want 1", embedder.embedded-2)
+	}
+	if got, _ := index.Get(Key{KindSummary, "a"}); !reflect.DeepEqual(got, []float32{-1, 0}) {
+		t.Errorf("changed item has vector %v", got)
+	}
+	if stored := store[Key{KindSummary, "a"}]; stored.textHash != hashText("opposite") {
+		t.Error("stored text hash was not updated")
+	}
+
+	put(t, index, item(KindSummary, "a", ""))
+	if _, ok := index.Get(Key{KindSummary, "a"}); ok {
+		t.Error("item with empty text is still indexed")
+	}
+	if _, ok := store[Key{KindSummary, "a"}]; ok {
+		t.Error("item with empty text is still stored")
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	vector := []float32{0, -1.5, 3.25, float32(math.Inf(1)), 1e-30}
+	decoded, err := Decode(Encode(vector))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, vector) {
+		t.Fatalf("got %v, want %v", decoded, vector)
+	}
+
+	if _, err := Decode([]byte{1, 2, 3}); err == nil {
+		t.Fatal("decoded a truncated vector")
+	}
+}
+
+func TestCosine(t *testing.T) {
+	cases := []struct {
+		a, b []float32
+		want float64
+	}{
+		{[]float32{1, 2}, []float32{2, 4}, 1},
+		{[]float32{1, 0}, []float32{0, 1}, 0},
+		{[]float32{1, 1}, []float32{-1, -1}, -1},
+		{[]float32{0, 0}, []float32{1, 0}, 0},
+		{[]float32{1, 0}, []float32{1, 0, 0}, 0},
+	}
+
+	for _, c := range cases {
+		if got := Cosine(c.a, c.b); math.Abs(got-c.want) > 1e-6 {
+			t.Errorf("Cosine(%v, %v) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}